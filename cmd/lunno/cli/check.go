@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"lunno/internal/typechecker"
+	"os"
+)
+
+type CheckCommand struct {
+	explainCache *bool
+	noCache      *bool
+}
+
+func (c *CheckCommand) Name() string {
+	return "check"
+}
+
+func (c *CheckCommand) Description() string {
+	return "Typecheck a Lunno source file"
+}
+
+func (c *CheckCommand) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.Name(), flag.ExitOnError)
+	c.explainCache = fs.Bool("explain-cache", false, "Report module interface cache hits and misses")
+	c.noCache = fs.Bool("no-cache", false, "Do not read or write module interface files")
+	return fs
+}
+
+func (c *CheckCommand) Run(args []string) {
+	fs := c.FlagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return
+	}
+	files := fs.Args()
+	if len(files) < 1 {
+		fmt.Println("Please specify a source file to check")
+		os.Exit(1)
+	}
+	cache := newCache(*c.noCache)
+	_, _, fatal := checkFile(files[0], typechecker.Config{Cache: cache})
+	if *c.explainCache {
+		if cache == nil {
+			fmt.Println("Module cache disabled")
+		} else {
+			fmt.Printf("Module cache %s: %d hit(s), %d miss(es)\n", cache.Dir, cache.Hits(), cache.Misses())
+			for _, e := range cache.Events {
+				if e.Hit {
+					fmt.Printf("  hit   %-12s %s\n", e.Module, e.Hash[:12])
+				} else {
+					fmt.Printf("  miss  %-12s %s (%s)\n", e.Module, e.Hash[:12], e.Reason)
+				}
+			}
+		}
+	}
+	if fatal > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("No errors found")
+}
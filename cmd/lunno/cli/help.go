@@ -6,6 +6,7 @@ import (
 
 var commands = []Command{
 	&RunCommand{},
+	&CheckCommand{},
 	&VersionCommand{},
 	&LspCommand{},
 }
@@ -1,16 +1,22 @@
 package cli
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"lunno/internal/decision"
 	"lunno/internal/lexer"
 	"lunno/internal/parser"
 	"lunno/internal/typechecker"
 	"os"
+	"path/filepath"
 )
 
 type RunCommand struct {
-	dumpAST *bool
+	dumpAST   *bool
+	dumpMatch *bool
+	dumpTypes *bool
+	noCache   *bool
 }
 
 func (c *RunCommand) Name() string {
@@ -24,6 +30,9 @@ func (c *RunCommand) Description() string {
 func (c *RunCommand) FlagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet(c.Name(), flag.ExitOnError)
 	c.dumpAST = fs.Bool("dump-ast", false, "Print AST of program")
+	c.dumpMatch = fs.Bool("dump-match", false, "Print the decision tree compiled for each match")
+	c.dumpTypes = fs.Bool("dump-types", false, "Print the inferred type of every name the program binds")
+	c.noCache = fs.Bool("no-cache", false, "Do not read or write module interface files")
 	return fs
 }
 
@@ -38,12 +47,40 @@ func (c *RunCommand) Run(args []string) {
 		fmt.Println("Please specify a source file to run")
 		os.Exit(1)
 	}
-	filename := files[0]
+	program, result, fatal := checkFile(files[0], typechecker.Config{
+		Cache: newCache(*c.noCache),
+	})
+	if fatal > 0 {
+		os.Exit(1)
+	}
+	if *c.dumpTypes {
+		fmt.Print(typechecker.DumpTypes(program, result))
+		return
+	}
+	if *c.dumpMatch {
+		for _, m := range result.Matches {
+			span := m.Match.Position.Span()
+			fmt.Printf("match at %s:%d:%d\n", span.File, span.Line, span.Column)
+			fmt.Print(decision.Dump(m.Tree))
+		}
+		return
+	}
+	if *c.dumpAST {
+		fmt.Println(parser.DumpProgram(program))
+		return
+	}
+	fmt.Println("Program ran successfully!")
+}
+
+// checkFile parses and typechecks a file, printing every diagnostic. It
+// exits on lexing and parse errors, but returns the number of type errors
+// so the caller can report more before exiting.
+func checkFile(filename string, config typechecker.Config) (*parser.Program, *typechecker.Result, int) {
 	source, err := os.ReadFile(filename)
 	if err != nil {
 		_, err := fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", filename, err)
 		if err != nil {
-			return
+			return nil, nil, 1
 		}
 		os.Exit(1)
 	}
@@ -51,7 +88,7 @@ func (c *RunCommand) Run(args []string) {
 	if err != nil {
 		_, err := fmt.Fprintf(os.Stderr, "Lexing error: %v\n", err)
 		if err != nil {
-			return
+			return nil, nil, 1
 		}
 		os.Exit(1)
 	}
@@ -63,17 +100,49 @@ func (c *RunCommand) Run(args []string) {
 		}
 		os.Exit(1)
 	}
-	typeErrors := typechecker.Check(program)
-	if len(typeErrors) > 0 {
-		fmt.Printf("Type errors (%d):\n", len(typeErrors))
-		for _, err := range typeErrors {
-			fmt.Println(" ", err)
+	config.SearchPaths = typechecker.SearchPaths(filename)
+	result := typechecker.Check(program, config)
+	sources := map[string][]rune{filename: []rune(string(source))}
+	readSource := func(file string) []rune {
+		if text, ok := sources[file]; ok {
+			return text
 		}
-		os.Exit(1)
+		text, _ := os.ReadFile(file)
+		sources[file] = []rune(string(text))
+		return sources[file]
 	}
-	if *c.dumpAST {
-		fmt.Println(parser.DumpProgram(program))
-		return
+	fatal := 0
+	for _, err := range result.Errors {
+		var warning *typechecker.Warning
+		var typeErr *typechecker.TypeError
+		switch {
+		case errors.As(err, &warning):
+			fmt.Print(warning.Render(readSource))
+			continue
+		case errors.As(err, &typeErr):
+			fmt.Print(typeErr.Render(readSource))
+		default:
+			fmt.Println("error:", err)
+		}
+		fatal++
 	}
-	fmt.Println("Program ran successfully!")
+	if fatal > 0 {
+		fmt.Printf("%d type error(s)\n", fatal)
+	}
+	return program, result, fatal
+}
+
+func newCache(disabled bool) *typechecker.Cache {
+	if disabled {
+		return nil
+	}
+	dir := os.Getenv("LUNNO_CACHE")
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(base, "lunno")
+	}
+	return typechecker.NewCache(dir)
 }
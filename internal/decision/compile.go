@@ -0,0 +1,355 @@
+package decision
+
+import (
+	"lunno/internal/parser"
+)
+
+type Constructor struct {
+	Name   string
+	Tag    int
+	Arity  int
+	Span   int
+	Fields []string
+	// Newtype marks the constructor of a newtype, which is represented
+	// as the value it wraps.
+	Newtype bool
+}
+
+// Lookup resolves a constructor or record pattern to its tag and the
+// number of constructors its type has. For records, Fields gives the
+// declared field order.
+type Lookup func(p parser.Pattern) (Constructor, bool)
+
+type test struct {
+	kind   TestKind
+	name   string
+	value  any
+	tag    int
+	length int
+	arity  int
+	span   int
+}
+
+type pattern struct {
+	test  *test
+	args  []*pattern
+	alts  []*pattern
+	binds []Binding
+}
+
+type row struct {
+	pats     []*pattern
+	arm      int
+	guarded  bool
+	bindings []Binding
+}
+
+func (t *test) same(other *test) bool {
+	if t.kind != other.kind {
+		return false
+	}
+	switch t.kind {
+	case TestTag:
+		return t.tag == other.tag
+	case TestLength, TestMinLength:
+		return t.length == other.length
+	case TestLiteral:
+		return t.value == other.value
+	}
+	return t.name == other.name
+}
+
+func Compile(m *parser.MatchExpression, lookup Lookup) Tree {
+	var rows []row
+	for i, arm := range m.Arms {
+		rows = append(rows, row{
+			pats:    []*pattern{lower(arm.Pattern, Occurrence{}, lookup)},
+			arm:     i,
+			guarded: arm.Guard != nil,
+		})
+	}
+	return compile(rows, []Occurrence{{}})
+}
+
+func lower(p parser.Pattern, path Occurrence, lookup Lookup) *pattern {
+	switch p := p.(type) {
+	case *parser.IdentifierPattern:
+		return &pattern{binds: []Binding{{Name: p.Name, Path: path}}}
+	case *parser.AsPattern:
+		out := lower(p.Pattern, path, lookup)
+		out.binds = append(out.binds, Binding{Name: p.Name, Path: path})
+		return out
+	case *parser.OrPattern:
+		out := &pattern{}
+		for _, alt := range p.Alternatives {
+			out.alts = append(out.alts, lower(alt, path, lookup))
+		}
+		return out
+	case *parser.NilPattern:
+		return &pattern{test: &test{kind: TestLength}}
+	case *parser.LiteralPattern:
+		return &pattern{test: literalTest(p.Value)}
+	case *parser.ListPattern:
+		out := &pattern{test: &test{kind: TestLength, length: len(p.Elements), arity: len(p.Elements)}}
+		for i, el := range p.Elements {
+			out.args = append(out.args, lower(el, path.child(i), lookup))
+		}
+		if p.Rest != nil {
+			out.test.kind = TestMinLength
+			if id, ok := p.Rest.(*parser.IdentifierPattern); ok {
+				out.binds = append(out.binds, Binding{Name: id.Name, Path: path, Drop: len(p.Elements)})
+			}
+		}
+		return out
+	case *parser.TuplePattern:
+		out := &pattern{test: &test{kind: TestTuple, arity: len(p.Elements), span: 1}}
+		for i, el := range p.Elements {
+			out.args = append(out.args, lower(el, path.child(i), lookup))
+		}
+		return out
+	case *parser.RecordPattern:
+		// Records have a single shape, so like tuples they need no test.
+		c, ok := lookup(p)
+		if !ok {
+			return &pattern{}
+		}
+		out := &pattern{test: &test{kind: TestTuple, arity: len(c.Fields), span: 1}}
+		for range c.Fields {
+			out.args = append(out.args, &pattern{})
+		}
+		for _, f := range p.Fields {
+			for i, name := range c.Fields {
+				if name == f.Name.Lexeme {
+					out.args[i] = lower(f.Pattern, path.child(i), lookup)
+				}
+			}
+		}
+		return out
+	case *parser.ConstructorPattern:
+		c, ok := lookup(p)
+		if !ok {
+			return &pattern{}
+		}
+		if c.Newtype && len(p.Arguments) == 1 {
+			// There is no wrapper to test or look inside, so the
+			// argument matches the value itself.
+			return lower(p.Arguments[0], path, lookup)
+		}
+		out := &pattern{test: &test{kind: TestTag, name: c.Name, tag: c.Tag, arity: c.Arity, span: c.Span}}
+		for i, a := range p.Arguments {
+			out.args = append(out.args, lower(a, path.child(i), lookup))
+		}
+		return out
+	}
+	return &pattern{}
+}
+
+func literalTest(e parser.Expression) *test {
+	switch lit := e.(type) {
+	case *parser.BooleanLiteral:
+		if lit.Value {
+			return &test{kind: TestLiteral, name: "true", value: true, span: 2}
+		}
+		return &test{kind: TestLiteral, name: "false", value: false, span: 2}
+	case *parser.IntegerLiteral:
+		return &test{kind: TestLiteral, name: lit.Raw, value: lit.Value}
+	case *parser.FloatLiteral:
+		return &test{kind: TestLiteral, name: lit.Raw, value: lit.Value}
+	case *parser.CharacterLiteral:
+		return &test{kind: TestLiteral, name: lit.Raw, value: lit.Value}
+	case *parser.StringLiteral:
+		return &test{kind: TestLiteral, name: lit.Raw, value: lit.Value}
+	}
+	return &test{kind: TestLiteral}
+}
+
+// expandOr splits rows so that no column holds an or-pattern. The expanded
+// rows stay adjacent, which preserves first-match order.
+func expandOr(rows []row) []row {
+	var out []row
+	for _, r := range rows {
+		out = append(out, expandRow(r)...)
+	}
+	return out
+}
+
+func expandRow(r row) []row {
+	for i, p := range r.pats {
+		if len(p.alts) == 0 {
+			continue
+		}
+		var out []row
+		for _, alt := range p.alts {
+			pats := make([]*pattern, len(r.pats))
+			copy(pats, r.pats)
+			pats[i] = &pattern{
+				test:  alt.test,
+				args:  alt.args,
+				alts:  alt.alts,
+				binds: append(append([]Binding{}, alt.binds...), p.binds...),
+			}
+			out = append(out, expandRow(row{pats: pats, arm: r.arm, guarded: r.guarded, bindings: r.bindings})...)
+		}
+		return out
+	}
+	return []row{r}
+}
+
+func compile(rows []row, occs []Occurrence) Tree {
+	rows = expandOr(rows)
+	if len(rows) == 0 {
+		return &Fail{}
+	}
+	first := rows[0]
+	col := -1
+	for i, p := range first.pats {
+		if p.test != nil {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		bindings := append([]Binding{}, first.bindings...)
+		for _, p := range first.pats {
+			bindings = append(bindings, p.binds...)
+		}
+		if first.guarded {
+			return &Guarded{Arm: first.arm, Bindings: bindings, Else: compile(rows[1:], occs)}
+		}
+		return &Leaf{Arm: first.arm, Bindings: bindings}
+	}
+
+	kind := first.pats[col].test.kind
+	if kind == TestTuple {
+		h := first.pats[col].test
+		return compile(specialize(rows, col, h), expand(occs, col, h.arity))
+	}
+	if kind == TestMinLength {
+		for _, r := range rows {
+			if t := r.pats[col].test; t != nil && t.kind == TestLength {
+				kind = TestLength
+				break
+			}
+		}
+		if kind == TestMinLength {
+			return compileMinLength(rows, col, occs)
+		}
+	}
+	var heads []*test
+	for _, r := range rows {
+		t := r.pats[col].test
+		if t == nil || t.kind != kind {
+			continue
+		}
+		seen := false
+		for _, h := range heads {
+			if h.same(t) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			heads = append(heads, t)
+		}
+	}
+
+	sw := &Switch{Path: occs[col], Kind: kind}
+	for _, h := range heads {
+		sw.Cases = append(sw.Cases, Case{
+			Name:   h.name,
+			Value:  h.value,
+			Tag:    h.tag,
+			Length: h.length,
+			Arity:  h.arity,
+			Body:   compile(specialize(rows, col, h), expand(occs, col, h.arity)),
+		})
+	}
+	if heads[0].span == 0 || len(heads) < heads[0].span {
+		sw.Default = compile(defaultRows(rows, col, kind), occs)
+	}
+	return sw
+}
+
+// compileMinLength handles a list column once no row needs an exact
+// length. Testing for the longest prefix first lets every rest pattern
+// share one case; shorter prefixes fall through to the default.
+func compileMinLength(rows []row, col int, occs []Occurrence) Tree {
+	n := 0
+	for _, r := range rows {
+		if t := r.pats[col].test; t != nil && t.length > n {
+			n = t.length
+		}
+	}
+	h := &test{kind: TestMinLength, length: n, arity: n}
+	body := compile(specialize(rows, col, h), expand(occs, col, n))
+	if n == 0 {
+		return body
+	}
+	var rest []row
+	for _, r := range rows {
+		if t := r.pats[col].test; t == nil || t.length < n {
+			rest = append(rest, r)
+		}
+	}
+	return &Switch{
+		Path:    occs[col],
+		Kind:    TestMinLength,
+		Cases:   []Case{{Length: n, Arity: n, Body: body}},
+		Default: compile(rest, occs),
+	}
+}
+
+func specialize(rows []row, col int, h *test) []row {
+	var out []row
+	for _, r := range rows {
+		p := r.pats[col]
+		var args []*pattern
+		switch {
+		case p.test == nil:
+			args = make([]*pattern, h.arity)
+			for i := range args {
+				args[i] = &pattern{}
+			}
+		case p.test.same(h):
+			args = p.args
+		case p.test.kind == TestMinLength && p.test.length <= h.length &&
+			(h.kind == TestLength || h.kind == TestMinLength):
+			args = append([]*pattern{}, p.args...)
+			for len(args) < h.arity {
+				args = append(args, &pattern{})
+			}
+		default:
+			continue
+		}
+		pats := make([]*pattern, 0, len(r.pats)-1+len(args))
+		pats = append(pats, r.pats[:col]...)
+		pats = append(pats, args...)
+		pats = append(pats, r.pats[col+1:]...)
+		bindings := append(append([]Binding{}, r.bindings...), p.binds...)
+		out = append(out, row{pats: pats, arm: r.arm, guarded: r.guarded, bindings: bindings})
+	}
+	return out
+}
+
+// defaultRows keeps the rows that can still match once every tested case
+// of the given kind has failed. The column is kept so that tests of a
+// different kind in the same position are still performed.
+func defaultRows(rows []row, col int, kind TestKind) []row {
+	var out []row
+	for _, r := range rows {
+		if t := r.pats[col].test; t != nil && t.kind == kind {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func expand(occs []Occurrence, col, arity int) []Occurrence {
+	out := make([]Occurrence, 0, len(occs)-1+arity)
+	out = append(out, occs[:col]...)
+	for i := 0; i < arity; i++ {
+		out = append(out, occs[col].child(i))
+	}
+	return append(out, occs[col+1:]...)
+}
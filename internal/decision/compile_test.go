@@ -0,0 +1,233 @@
+package decision_test
+
+import (
+	"lunno/internal/decision"
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+	"testing"
+)
+
+// stubLookup resolves constructors as the typechecker would for these
+// declarations:
+//
+//	class Option[T] { None Some(T) }
+//	class Shape { Circle(int) Square(int) Rect(int, int) }
+//	newtype Meters = int
+//	record Point { x: int, y: int }
+func stubLookup(p parser.Pattern) (decision.Constructor, bool) {
+	ctors := map[string]decision.Constructor{
+		"None":   {Name: "None", Tag: 0, Arity: 0, Span: 2},
+		"Some":   {Name: "Some", Tag: 1, Arity: 1, Span: 2},
+		"Circle": {Name: "Circle", Tag: 0, Arity: 1, Span: 3},
+		"Square": {Name: "Square", Tag: 1, Arity: 1, Span: 3},
+		"Rect":   {Name: "Rect", Tag: 2, Arity: 2, Span: 3},
+		"Meters": {Name: "Meters", Arity: 1, Span: 1, Newtype: true},
+	}
+	switch p := p.(type) {
+	case *parser.ConstructorPattern:
+		c, ok := ctors[p.Name]
+		return c, ok
+	case *parser.RecordPattern:
+		return decision.Constructor{Name: "Point", Arity: 2, Span: 1, Fields: []string{"x", "y"}}, true
+	}
+	return decision.Constructor{}, false
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		lookup   decision.Lookup
+		expected string
+	}{
+		{
+			name:  "literals with default",
+			input: `match 1 with { | 0 -> 1 | 1 | 2 -> 2 | n -> n }`,
+			expected: `└─ Switch value $
+   ├─ Case 0
+   │  └─ Arm 0
+   ├─ Case 1
+   │  └─ Arm 1
+   ├─ Case 2
+   │  └─ Arm 1
+   └─ Default
+      └─ Arm 2 [n=$]
+`,
+		},
+		{
+			name:  "literals written two ways",
+			input: `match 1.0 with { | 1.0 -> 0 | 1.00 -> 1 | 2.5 -> 2 | _ -> 3 }`,
+			expected: `└─ Switch value $
+   ├─ Case 1.0
+   │  └─ Arm 0
+   ├─ Case 2.5
+   │  └─ Arm 2
+   └─ Default
+      └─ Arm 3
+`,
+		},
+		{
+			name:  "escaped strings",
+			input: `match s with { | "A" -> 0 | "\x41" -> 1 | "B" -> 2 | _ -> 3 }`,
+			expected: `└─ Switch value $
+   ├─ Case "A"
+   │  └─ Arm 0
+   ├─ Case "B"
+   │  └─ Arm 2
+   └─ Default
+      └─ Arm 3
+`,
+		},
+		{
+			name:  "bools are exhaustive",
+			input: `match true with { | true -> 1 | false -> 0 }`,
+			expected: `└─ Switch value $
+   ├─ Case true
+   │  └─ Arm 0
+   └─ Case false
+      └─ Arm 1
+`,
+		},
+		{
+			name:  "list lengths and guards",
+			input: `match [] with { | nil -> 0 | [x] when x -> 1 | [_, y] -> y | _ -> 3 }`,
+			expected: `└─ Switch length $
+   ├─ Case length 0
+   │  └─ Arm 0
+   ├─ Case length 1
+   │  └─ Guard arm 1 [x=$.0]
+   │     └─ Else
+   │        └─ Arm 3
+   ├─ Case length 2
+   │  └─ Arm 2 [y=$.1]
+   └─ Default
+      └─ Arm 3
+`,
+		},
+		{
+			name:  "rest patterns",
+			input: `match [] with { | [] -> 0 | [x, ...rest] -> 1 | [a, b] -> 2 }`,
+			expected: `└─ Switch length $
+   ├─ Case length 0
+   │  └─ Arm 0
+   ├─ Case length 2
+   │  └─ Arm 1 [rest=$[1:], x=$.0]
+   └─ Default
+      └─ Switch length $
+         ├─ Case length >= 1
+         │  └─ Arm 1 [rest=$[1:], x=$.0]
+         └─ Default
+            └─ Fail
+`,
+		},
+		{
+			name:   "constructor tags",
+			input:  `match o with { | Some(x) -> x | None -> 0 }`,
+			lookup: stubLookup,
+			expected: `└─ Switch tag $
+   ├─ Case Some (tag 1)
+   │  └─ Arm 0 [x=$.0]
+   └─ Case None (tag 0)
+      └─ Arm 1
+`,
+		},
+		{
+			name:   "missing constructor fails",
+			input:  `match s with { | Circle(r) -> r | Rect(w, h) -> w }`,
+			lookup: stubLookup,
+			expected: `└─ Switch tag $
+   ├─ Case Circle (tag 0)
+   │  └─ Arm 0 [r=$.0]
+   ├─ Case Rect (tag 2)
+   │  └─ Arm 1 [w=$.0, h=$.1]
+   └─ Default
+      └─ Fail
+`,
+		},
+		{
+			name:   "wildcard covers the other constructors",
+			input:  `match s with { | Square(n) -> n | _ -> 0 }`,
+			lookup: stubLookup,
+			expected: `└─ Switch tag $
+   ├─ Case Square (tag 1)
+   │  └─ Arm 0 [n=$.0]
+   └─ Default
+      └─ Arm 1
+`,
+		},
+		{
+			name:   "nested constructors",
+			input:  `match o with { | Some(Some(x)) -> x | Some(None) -> 1 | None -> 0 }`,
+			lookup: stubLookup,
+			expected: `└─ Switch tag $
+   ├─ Case Some (tag 1)
+   │  └─ Switch tag $.0
+   │     ├─ Case Some (tag 1)
+   │     │  └─ Arm 0 [x=$.0.0]
+   │     └─ Case None (tag 0)
+   │        └─ Arm 1
+   └─ Case None (tag 0)
+      └─ Arm 2
+`,
+		},
+		{
+			name:   "newtypes are unwrapped",
+			input:  `match m with { | Meters(0) -> 0 | Meters(n) -> n }`,
+			lookup: stubLookup,
+			expected: `└─ Switch value $
+   ├─ Case 0
+   │  └─ Arm 0
+   └─ Default
+      └─ Arm 1 [n=$]
+`,
+		},
+		{
+			name:   "tuples",
+			input:  `match (a, b) with { | (true, Some(x)) -> x | (false, _) -> 1 | (_, None) -> 0 }`,
+			lookup: stubLookup,
+			expected: `└─ Switch value $.0
+   ├─ Case true
+   │  └─ Switch tag $.1
+   │     ├─ Case Some (tag 1)
+   │     │  └─ Arm 0 [x=$.1.0]
+   │     └─ Case None (tag 0)
+   │        └─ Arm 2
+   └─ Case false
+      └─ Arm 1
+`,
+		},
+		{
+			name:   "records in declared field order",
+			input:  `match p with { | { y: 0, x: a } -> a | { x: 0 } -> 1 | _ -> 2 }`,
+			lookup: stubLookup,
+			expected: `└─ Switch value $.1
+   ├─ Case 0
+   │  └─ Arm 0 [a=$.0]
+   └─ Default
+      └─ Switch value $.0
+         ├─ Case 0
+         │  └─ Arm 1
+         └─ Default
+            └─ Arm 2
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lx, tokens, err := lexer.Tokenize(tt.input, "test.ln")
+			if err != nil {
+				t.Fatalf("unexpected lexing error: %v", err)
+			}
+			program, errs := parser.ParseProgram(tokens, lx)
+			if len(errs) > 0 {
+				t.Fatalf("unexpected parse errors: %v", errs)
+			}
+			m := program.Expressions[0].(*parser.MatchExpression)
+			got := decision.Dump(decision.Compile(m, tt.lookup))
+			if got != tt.expected {
+				t.Errorf("expected tree:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
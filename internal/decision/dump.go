@@ -0,0 +1,86 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+)
+
+func Dump(t Tree) string {
+	return dumpTree(t, "", true)
+}
+
+func node(indent string, last bool, label string) (string, string) {
+	branch := "├─ "
+	next := indent + "│  "
+	if last {
+		branch = "└─ "
+		next = indent + "   "
+	}
+	return indent + branch + label + "\n", next
+}
+
+func dumpBindings(bindings []Binding) string {
+	if len(bindings) == 0 {
+		return ""
+	}
+	parts := make([]string, len(bindings))
+	for i, b := range bindings {
+		parts[i] = b.Name + "=" + b.Path.String()
+		if b.Drop > 0 {
+			parts[i] += fmt.Sprintf("[%d:]", b.Drop)
+		}
+	}
+	return " [" + strings.Join(parts, ", ") + "]"
+}
+
+func dumpTree(t Tree, indent string, last bool) string {
+	switch n := t.(type) {
+	case *Leaf:
+		line, _ := node(indent, last, fmt.Sprintf("Arm %d%s", n.Arm, dumpBindings(n.Bindings)))
+		return line
+	case *Guarded:
+		line, next := node(indent, last, fmt.Sprintf("Guard arm %d%s", n.Arm, dumpBindings(n.Bindings)))
+		eLine, eNext := node(next, true, "Else")
+		return line + eLine + dumpTree(n.Else, eNext, true)
+	case *Fail:
+		line, _ := node(indent, last, "Fail")
+		return line
+	case *Switch:
+		var label string
+		switch n.Kind {
+		case TestTag:
+			label = "Switch tag " + n.Path.String()
+		case TestLiteral:
+			label = "Switch value " + n.Path.String()
+		case TestLength, TestMinLength:
+			label = "Switch length " + n.Path.String()
+		}
+		line, next := node(indent, last, label)
+		var out strings.Builder
+		out.WriteString(line)
+		for i, c := range n.Cases {
+			var caseLabel string
+			switch n.Kind {
+			case TestTag:
+				caseLabel = fmt.Sprintf("Case %s (tag %d)", c.Name, c.Tag)
+			case TestLiteral:
+				caseLabel = "Case " + c.Name
+			case TestLength:
+				caseLabel = fmt.Sprintf("Case length %d", c.Length)
+			case TestMinLength:
+				caseLabel = fmt.Sprintf("Case length >= %d", c.Length)
+			}
+			cLine, cNext := node(next, i == len(n.Cases)-1 && n.Default == nil, caseLabel)
+			out.WriteString(cLine)
+			out.WriteString(dumpTree(c.Body, cNext, true))
+		}
+		if n.Default != nil {
+			dLine, dNext := node(next, true, "Default")
+			out.WriteString(dLine)
+			out.WriteString(dumpTree(n.Default, dNext, true))
+		}
+		return out.String()
+	}
+	line, _ := node(indent, last, fmt.Sprintf("<unknown tree %T>", t))
+	return line
+}
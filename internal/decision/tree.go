@@ -0,0 +1,86 @@
+package decision
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Tree interface {
+	isTree()
+}
+
+type TestKind int
+
+const (
+	TestTag TestKind = iota
+	TestLiteral
+	TestLength
+	TestTuple
+	// TestMinLength checks that a list has at least Case.Length elements.
+	TestMinLength
+)
+
+type Occurrence []int
+
+// Binding names the value at Path. When Drop is set the value is the list
+// at Path without its first Drop elements, which backends can share
+// rather than copy.
+type Binding struct {
+	Name string
+	Path Occurrence
+	Drop int
+}
+
+type Leaf struct {
+	Arm      int
+	Bindings []Binding
+}
+
+type Guarded struct {
+	Arm      int
+	Bindings []Binding
+	Else     Tree
+}
+
+type Fail struct{}
+
+type Case struct {
+	Name string
+	// Value is what a literal case matches: a bool, int64, float64, string
+	// or byte. Literals written differently but equal share one case,
+	// named after the first of them.
+	Value  any
+	Tag    int
+	Length int
+	Arity  int
+	Body   Tree
+}
+
+type Switch struct {
+	Path    Occurrence
+	Kind    TestKind
+	Cases   []Case
+	Default Tree
+}
+
+func (*Leaf) isTree()    {}
+func (*Guarded) isTree() {}
+func (*Fail) isTree()    {}
+func (*Switch) isTree()  {}
+
+func (o Occurrence) String() string {
+	var out strings.Builder
+	out.WriteString("$")
+	for _, i := range o {
+		out.WriteString(".")
+		out.WriteString(strconv.Itoa(i))
+	}
+	return out.String()
+}
+
+func (o Occurrence) child(i int) Occurrence {
+	out := make(Occurrence, len(o)+1)
+	copy(out, o)
+	out[len(o)] = i
+	return out
+}
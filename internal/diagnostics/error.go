@@ -2,19 +2,32 @@ package diagnostics
 
 import "fmt"
 
-func Report(source []rune, span Span, msg string) error {
-	lineText := getLineText(source, span.Line)
-	caret := makeCaret(span.Column)
+// Label attaches a note to a secondary span, such as the place a
+// conflicting type came from.
+type Label struct {
+	Span    Span
+	Message string
+}
 
-	fmt.Printf(
-		"error: %s\n  --> %s:%d:%d\n   |\n%2d | %s\n   | %s\n",
-		msg,
-		span.File, span.Line, span.Column,
-		span.Line, lineText, caret,
-	)
+func Report(source []rune, span Span, msg string) error {
+	fmt.Print("error: " + msg + "\n" + Snippet(source, span, ""))
 
 	return fmt.Errorf(
 		"%s:%d:%d: %s",
 		span.File, span.Line, span.Column, msg,
 	)
 }
+
+// Snippet formats the source line at span with a caret under its column,
+// followed by note when it is not empty.
+func Snippet(source []rune, span Span, note string) string {
+	caret := makeCaret(span.Column)
+	if note != "" {
+		caret += " " + note
+	}
+	return fmt.Sprintf(
+		"  --> %s:%d:%d\n   |\n%2d | %s\n   | %s\n",
+		span.File, span.Line, span.Column,
+		span.Line, getLineText(source, span.Line), caret,
+	)
+}
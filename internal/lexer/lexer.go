@@ -63,7 +63,7 @@ func (lexer *Lexer) Next() Token {
 	if lexer.position >= lexer.sourceLen {
 		return lexer.makeToken(EndOfFile, "")
 	}
-	for opLen := uint16(2); opLen > 0; opLen-- {
+	for opLen := uint16(3); opLen > 0; opLen-- {
 		if lexer.position+opLen <= lexer.sourceLen {
 			sub := string(lexer.Source[lexer.position : lexer.position+opLen])
 			if tt, ok := multiCharOperators[sub]; ok {
@@ -38,6 +38,36 @@ func TestLexer(t *testing.T) {
 			expected: []lexer.TokenType{lexer.Illegal, lexer.EndOfFile},
 			lexemes:  []string{"12.", ""},
 		},
+		{
+			name:     "visibility modifier",
+			input:    "pub let x",
+			expected: []lexer.TokenType{lexer.KwPub, lexer.KwLet, lexer.Identifier, lexer.EndOfFile},
+			lexemes:  []string{"pub", "let", "x", ""},
+		},
+		{
+			name:     "qualified constructor",
+			input:    "class Option.Some",
+			expected: []lexer.TokenType{lexer.KwClass, lexer.Identifier, lexer.Dot, lexer.Identifier, lexer.EndOfFile},
+			lexemes:  []string{"class", "Option", ".", "Some", ""},
+		},
+		{
+			name:     "rest pattern",
+			input:    "[x, ...rest]",
+			expected: []lexer.TokenType{lexer.LeftBracket, lexer.Identifier, lexer.Comma, lexer.Ellipsis, lexer.Identifier, lexer.RightBracket, lexer.EndOfFile},
+			lexemes:  []string{"[", "x", ",", "...", "rest", "]", ""},
+		},
+		{
+			name:     "pipeline operators",
+			input:    "xs |> f >> g",
+			expected: []lexer.TokenType{lexer.Identifier, lexer.PipeForward, lexer.Identifier, lexer.Compose, lexer.Identifier, lexer.EndOfFile},
+			lexemes:  []string{"xs", "|>", "f", ">>", "g", ""},
+		},
+		{
+			name:     "logical and modulo operators",
+			input:    "!a && not b || c % 2",
+			expected: []lexer.TokenType{lexer.Bang, lexer.Identifier, lexer.And, lexer.KwNot, lexer.Identifier, lexer.Or, lexer.Identifier, lexer.Percent, lexer.Int, lexer.EndOfFile},
+			lexemes:  []string{"!", "a", "&&", "not", "b", "||", "c", "%", "2", ""},
+		},
 		{
 			name:     "strings",
 			input:    `"hello" "a\nb"`,
@@ -64,9 +94,21 @@ func TestLexer(t *testing.T) {
 		},
 		{
 			name:     "invalid character",
-			input:    "@",
+			input:    "$",
 			expected: []lexer.TokenType{lexer.Illegal, lexer.EndOfFile},
-			lexemes:  []string{"@", ""},
+			lexemes:  []string{"$", ""},
+		},
+		{
+			name:     "as-pattern",
+			input:    "p @ _",
+			expected: []lexer.TokenType{lexer.Identifier, lexer.At, lexer.Underscore, lexer.EndOfFile},
+			lexemes:  []string{"p", "@", "_", ""},
+		},
+		{
+			name:     "hole",
+			input:    "?rest",
+			expected: []lexer.TokenType{lexer.Question, lexer.Identifier, lexer.EndOfFile},
+			lexemes:  []string{"?", "rest", ""},
 		},
 		{
 			name:     "unterminated string",
@@ -37,6 +37,10 @@ const (
 	Minus
 	Asterisk
 	Slash
+	Percent
+	Bang
+	And
+	Or
 
 	Assign
 	Equal
@@ -47,9 +51,16 @@ const (
 	GreaterThanOrEqual
 
 	Comma
+	Dot
+	Ellipsis
 	Colon
+	ColonAssign
 	Arrow
 	Pipe
+	PipeForward
+	Compose
+	At
+	Question
 	Underscore
 
 	KwLet
@@ -63,6 +74,17 @@ const (
 	KwWhen
 	KwImport
 	KwFrom
+	KwPub
+	KwClass
+	KwRecord
+	KwNot
+	KwForall
+	KwType
+	KwNewtype
+	KwAnd
+	KwTry
+	KwRecover
+	KwLazy
 	KwInt
 	KwFloat
 	KwString
@@ -75,26 +97,37 @@ const (
 )
 
 var keywords = map[string]TokenType{
-	"let":    KwLet,
-	"rec":    KwRec,
-	"fn":     KwFn,
-	"if":     KwIf,
-	"then":   KwThen,
-	"else":   KwElse,
-	"match":  KwMatch,
-	"with":   KwWith,
-	"when":   KwWhen,
-	"import": KwImport,
-	"from":   KwFrom,
-	"int":    KwInt,
-	"float":  KwFloat,
-	"string": KwString,
-	"char":   KwChar,
-	"bool":   KwBool,
-	"unit":   KwUnit,
-	"nil":    KwNil,
-	"true":   Bool,
-	"false":  Bool,
+	"let":     KwLet,
+	"rec":     KwRec,
+	"fn":      KwFn,
+	"if":      KwIf,
+	"then":    KwThen,
+	"else":    KwElse,
+	"match":   KwMatch,
+	"with":    KwWith,
+	"when":    KwWhen,
+	"import":  KwImport,
+	"from":    KwFrom,
+	"pub":     KwPub,
+	"class":   KwClass,
+	"record":  KwRecord,
+	"not":     KwNot,
+	"forall":  KwForall,
+	"type":    KwType,
+	"newtype": KwNewtype,
+	"and":     KwAnd,
+	"try":     KwTry,
+	"recover": KwRecover,
+	"lazy":    KwLazy,
+	"int":     KwInt,
+	"float":   KwFloat,
+	"string":  KwString,
+	"char":    KwChar,
+	"bool":    KwBool,
+	"unit":    KwUnit,
+	"nil":     KwNil,
+	"true":    Bool,
+	"false":   Bool,
 }
 
 func Keywords() map[string]TokenType {
@@ -106,28 +139,43 @@ func Keywords() map[string]TokenType {
 }
 
 var precedences = map[TokenType]int{
-	Arrow:    -1,
-	Colon:    1,
-	Equal:    2,
-	NotEqual: 2,
-
-	LessThan:           3,
-	GreaterThan:        3,
-	LessThanOrEqual:    3,
-	GreaterThanOrEqual: 3,
-
-	Plus:     4,
-	Minus:    4,
-	Asterisk: 5,
-	Slash:    5,
+	Arrow:       -1,
+	PipeForward: 0,
+	Compose:     1,
+	Colon:       1,
+	Or:          2,
+	And:         3,
+	Equal:       4,
+	NotEqual:    4,
+
+	LessThan:           5,
+	GreaterThan:        5,
+	LessThanOrEqual:    5,
+	GreaterThanOrEqual: 5,
+
+	Plus:     6,
+	Minus:    6,
+	Asterisk: 7,
+	Slash:    7,
+	Percent:  7,
 }
 
+// PrefixPrecedence binds prefix operators tighter than any binary operator,
+// so `-x * y` is `(-x) * y`.
+const PrefixPrecedence = 8
+
 var multiCharOperators = map[string]TokenType{
-	"==": Equal,
-	"!=": NotEqual,
-	"<=": LessThanOrEqual,
-	">=": GreaterThanOrEqual,
-	"->": Arrow,
+	"==":  Equal,
+	":=":  ColonAssign,
+	"!=":  NotEqual,
+	"<=":  LessThanOrEqual,
+	">=":  GreaterThanOrEqual,
+	"->":  Arrow,
+	"...": Ellipsis,
+	"|>":  PipeForward,
+	">>":  Compose,
+	"&&":  And,
+	"||":  Or,
 }
 
 var singleCharTokens = map[byte]TokenType{
@@ -135,9 +183,11 @@ var singleCharTokens = map[byte]TokenType{
 	'[': LeftBracket, ']': RightBracket,
 	'{': LeftBrace, '}': RightBrace,
 	'+': Plus, '-': Minus,
-	'*': Asterisk, '/': Slash,
-	':': Colon, ',': Comma,
+	'*': Asterisk, '/': Slash, '%': Percent,
+	'!': Bang,
+	':': Colon, ',': Comma, '.': Dot,
 	'=': Assign, '|': Pipe, '_': Underscore,
+	'@': At, '?': Question,
 	'<': LessThan, '>': GreaterThan,
 }
 
@@ -2,8 +2,11 @@ package lsp
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"lunno/internal/lexer"
 	"lunno/internal/parser"
+	"lunno/internal/typechecker"
 	"strings"
 )
 
@@ -31,8 +34,7 @@ func handleDidOpen(params json.RawMessage) {
 		return
 	}
 	docs[p.TextDocument.URI] = p.TextDocument.Text
-	updateSymbols(p.TextDocument.URI, p.TextDocument.Text, lx)
-	runDiagnostics(p.TextDocument.URI, lx)
+	analyze(p.TextDocument.URI)
 }
 
 func handleDidChange(params json.RawMessage) {
@@ -43,8 +45,7 @@ func handleDidChange(params json.RawMessage) {
 	}
 	if len(p.ContentChanges) > 0 {
 		docs[p.TextDocument.URI] = p.ContentChanges[0].Text
-		updateSymbols(p.TextDocument.URI, p.ContentChanges[0].Text, lx)
-		runDiagnostics(p.TextDocument.URI, lx)
+		analyze(p.TextDocument.URI)
 	}
 }
 
@@ -56,30 +57,36 @@ func handleShutdown(req RequestMessage) {
 	})
 }
 
-func runDiagnostics(uri string, lx *lexer.Lexer) {
-	text := docs[uri]
-	_, tokens, err := lexer.Tokenize(text, uri)
+// analyze parses and typechecks a document once, then updates its
+// symbols and publishes its diagnostics from that one result. Imports are
+// looked up the way the command line looks them up for a file.
+func analyze(uri string) {
+	_, tokens, err := lexer.Tokenize(docs[uri], uri)
 	if err != nil {
-		send(map[string]any{
-			"jsonrpc": "2.0",
-			"method":  "textDocument/publishDiagnostics",
-			"params": PublishDiagnosticsParams{
-				URI: uri,
-				Diagnostics: []Diagnostic{{
-					Severity: 1,
-					Message:  "Lexing error: " + err.Error(),
-					Range: Range{
-						Start: Position{Line: 0, Character: 0},
-						End:   Position{Line: 0, Character: 1},
-					},
-				}},
+		publishDiagnostics(uri, []Diagnostic{{
+			Severity: 1,
+			Message:  "Lexing error: " + err.Error(),
+			Range: Range{
+				Start: Position{Line: 0, Character: 0},
+				End:   Position{Line: 0, Character: 1},
 			},
-		})
+		}})
 		return
 	}
+	program, parseErrs := parser.ParseProgram(tokens, lx)
+	var result *typechecker.Result
+	if len(parseErrs) == 0 {
+		result = typechecker.Check(program, typechecker.Config{
+			SearchPaths: typechecker.SearchPaths(uriPath(uri)),
+		})
+	}
+	updateSymbols(uri, program, result)
+	runDiagnostics(uri, parseErrs, result)
+}
 
-	_, parseErrs := parser.ParseProgram(tokens, lx)
-
+// runDiagnostics publishes the parse errors of a document, or the type
+// errors and warnings in result when it parsed.
+func runDiagnostics(uri string, parseErrs []string, result *typechecker.Result) {
 	var diagnostics []Diagnostic
 	for _, e := range parseErrs {
 		diagnostics = append(diagnostics, Diagnostic{
@@ -91,7 +98,17 @@ func runDiagnostics(uri string, lx *lexer.Lexer) {
 			},
 		})
 	}
+	if result != nil {
+		for _, err := range result.Errors {
+			if d, ok := typeDiagnostic(uri, err); ok {
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+	publishDiagnostics(uri, diagnostics)
+}
 
+func publishDiagnostics(uri string, diagnostics []Diagnostic) {
 	send(map[string]any{
 		"jsonrpc": "2.0",
 		"method":  "textDocument/publishDiagnostics",
@@ -102,6 +119,36 @@ func runDiagnostics(uri string, lx *lexer.Lexer) {
 	})
 }
 
+// typeDiagnostic converts a type error or warning located in the document
+// at uri into a diagnostic at its span.
+func typeDiagnostic(uri string, err error) (Diagnostic, bool) {
+	severity := 1
+	var warning *typechecker.Warning
+	if errors.As(err, &warning) {
+		severity = 2
+	}
+	var te *typechecker.TypeError
+	if !errors.As(err, &te) || te.Span.File != uri {
+		return Diagnostic{}, false
+	}
+	message := fmt.Sprintf("[%s] %s", te.Code, te.Message)
+	if te.Expected != nil && te.Found != nil {
+		message += fmt.Sprintf(" (expected %s, found %s)", te.Expected, te.Found)
+	}
+	for _, note := range te.Notes {
+		message += "\n" + note
+	}
+	start := Position{Line: int(te.Span.Line) - 1, Character: int(te.Span.Column) - 1}
+	return Diagnostic{
+		Severity: severity,
+		Message:  message,
+		Range: Range{
+			Start: start,
+			End:   Position{Line: start.Line, Character: start.Character + 1},
+		},
+	}, true
+}
+
 func handleCompletion(req RequestMessage) {
 	var params CompletionParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -143,8 +190,9 @@ func handleCompletion(req RequestMessage) {
 				kind = 3
 			}
 			suggestions = append(suggestions, CompletionItem{
-				Label: sym.Name,
-				Kind:  kind,
+				Label:  sym.Name,
+				Kind:   kind,
+				Detail: sym.Type,
 			})
 		}
 	}
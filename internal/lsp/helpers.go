@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -66,3 +68,13 @@ func readMessage(r *bufio.Reader) ([]byte, error) {
 	_, err = io.ReadFull(r, body)
 	return body, err
 }
+
+// uriPath returns the file system path of a file:// URI, or the URI
+// itself for any other scheme.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
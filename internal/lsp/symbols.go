@@ -1,9 +1,9 @@
 package lsp
 
 import (
-	"fmt"
 	"lunno/internal/lexer"
 	"lunno/internal/parser"
+	"lunno/internal/typechecker"
 )
 
 type SymbolKind int
@@ -20,14 +20,14 @@ const (
 type Symbol struct {
 	Name string
 	Kind SymbolKind
+	// Type is the symbol's inferred type, when the document typechecks far
+	// enough to know it.
+	Type string
 }
 
-func updateSymbols(uri, text string, lx *lexer.Lexer) {
-	_, tokens, err := lexer.Tokenize(text, uri)
-	if err != nil {
-		return
-	}
-	program, errs := parser.ParseProgram(tokens, lx)
+// updateSymbols collects the names a document declares, with their types
+// when result holds them.
+func updateSymbols(uri string, program *parser.Program, result *typechecker.Result) {
 	var symbol []Symbol
 	var walk func(expr parser.Expression)
 	walk = func(expr parser.Expression) {
@@ -42,6 +42,27 @@ func updateSymbols(uri, text string, lx *lexer.Lexer) {
 				Name: expr.Name.Lexeme,
 				Kind: 1,
 			})
+		case *parser.ClassDeclarationExpression:
+			for _, ctor := range expr.Constructors {
+				symbol = append(symbol, Symbol{
+					Name: ctor.Name.Lexeme,
+					Kind: 1,
+				})
+			}
+		case *parser.RecordDeclarationExpression:
+			symbol = append(symbol, Symbol{
+				Name: expr.Name.Lexeme,
+				Kind: 1,
+			})
+		case *parser.NewtypeDeclarationExpression:
+			symbol = append(symbol, Symbol{
+				Name: expr.Name.Lexeme,
+				Kind: 1,
+			})
+		case *parser.RecursiveGroupExpression:
+			for _, d := range expr.Declarations {
+				walk(d)
+			}
 		case *parser.BlockExpression:
 			for _, sub := range expr.Expressions {
 				walk(sub)
@@ -64,26 +85,12 @@ func updateSymbols(uri, text string, lx *lexer.Lexer) {
 	for _, expr := range program.Expressions {
 		walk(expr)
 	}
-
-	symbols[uri] = symbol
-	var diagnostics []Diagnostic
-	for _, e := range errs {
-		diagnostics = append(diagnostics, Diagnostic{
-			Severity: 1,
-			Message:  fmt.Sprintf("%s:%d:%d: %s", uri, 1, 1, e),
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 0, Character: 1},
-			},
-		})
+	if result != nil {
+		for i, sym := range symbol {
+			if s, ok := result.Env[sym.Name]; ok {
+				symbol[i].Type = s.String()
+			}
+		}
 	}
-
-	send(map[string]any{
-		"jsonrpc": "2.0",
-		"method":  "textDocument/publishDiagnostics",
-		"params": PublishDiagnosticsParams{
-			URI:         uri,
-			Diagnostics: diagnostics,
-		},
-	})
+	symbols[uri] = symbol
 }
@@ -36,6 +36,27 @@ func (i *Identifier) NodeType() string {
 	return "Identifier"
 }
 
+// HoleExpression is a typed hole, `?name` or `_`, standing for code that
+// is not written yet. The typechecker reports the type it must have.
+// Name is empty for `_`.
+type HoleExpression struct {
+	Name     string
+	Position lexer.Token
+}
+
+func (h *HoleExpression) exprNode() {}
+func (h *HoleExpression) NodeType() string {
+	return "HoleExpression"
+}
+
+// Label returns the hole as it is written in the source.
+func (h *HoleExpression) Label() string {
+	if h.Name == "" {
+		return "_"
+	}
+	return "?" + h.Name
+}
+
 type IntegerLiteral struct {
 	Value    int64
 	Raw      string
@@ -60,6 +81,7 @@ func (f *FloatLiteral) NodeType() string {
 
 type StringLiteral struct {
 	Value    string
+	Raw      string
 	Position lexer.Token
 }
 
@@ -108,6 +130,16 @@ func (l *ListExpression) NodeType() string {
 	return "ListExpression"
 }
 
+type TupleExpression struct {
+	Elements []Expression
+	Position lexer.Token
+}
+
+func (t *TupleExpression) exprNode() {}
+func (t *TupleExpression) NodeType() string {
+	return "TupleExpression"
+}
+
 type IndexExpression struct {
 	Target   Expression
 	Index    Expression
@@ -119,6 +151,45 @@ func (i *IndexExpression) NodeType() string {
 	return "IndexExpression"
 }
 
+// TryExpression is `try { body } recover e -> handler`. If the body
+// panics, the handler runs with e bound to the panic. Binding is an
+// Underscore token when the panic is ignored.
+type TryExpression struct {
+	Body     Expression
+	Binding  lexer.Token
+	Handler  Expression
+	Position lexer.Token
+}
+
+func (t *TryExpression) exprNode() {}
+func (t *TryExpression) NodeType() string {
+	return "TryExpression"
+}
+
+// LazyExpression is `lazy body`: a suspended computation that runs body
+// the first time it is forced and keeps the result.
+type LazyExpression struct {
+	Body     Expression
+	Position lexer.Token
+}
+
+func (l *LazyExpression) exprNode() {}
+func (l *LazyExpression) NodeType() string {
+	return "LazyExpression"
+}
+
+// PropagateExpression is `target?`. It unwraps an `Ok` and returns an
+// `Err` from the enclosing function.
+type PropagateExpression struct {
+	Target   Expression
+	Position lexer.Token
+}
+
+func (p *PropagateExpression) exprNode() {}
+func (p *PropagateExpression) NodeType() string {
+	return "PropagateExpression"
+}
+
 type PrefixExpression struct {
 	Operator lexer.Token
 	Right    Expression
@@ -158,6 +229,7 @@ type VariableDeclarationExpression struct {
 	Type      TypeNode
 	Value     Expression
 	Recursive bool
+	Public    bool
 	Position  lexer.Token
 }
 
@@ -166,6 +238,22 @@ func (v *VariableDeclarationExpression) NodeType() string {
 	return "VariableDeclarationExpression"
 }
 
+// DestructuringDeclarationExpression is `let pattern = value`. A pattern
+// that can fail to match needs an Else block, which runs instead of the
+// rest of the scope and must not produce a value.
+type DestructuringDeclarationExpression struct {
+	Pattern  Pattern
+	Type     TypeNode
+	Value    Expression
+	Else     Expression
+	Position lexer.Token
+}
+
+func (d *DestructuringDeclarationExpression) exprNode() {}
+func (d *DestructuringDeclarationExpression) NodeType() string {
+	return "DestructuringDeclarationExpression"
+}
+
 type Parameter struct {
 	Name     lexer.Token
 	Type     TypeNode
@@ -183,9 +271,22 @@ func (f *FunctionLiteralExpression) NodeType() string {
 	return "FunctionLiteralExpression"
 }
 
+// RecursiveGroupExpression is `let rec f = ... and g = ...`. Each
+// declaration may refer to every other one in the group.
+type RecursiveGroupExpression struct {
+	Declarations []Expression
+	Position     lexer.Token
+}
+
+func (r *RecursiveGroupExpression) exprNode() {}
+func (r *RecursiveGroupExpression) NodeType() string {
+	return "RecursiveGroupExpression"
+}
+
 type FunctionDeclarationExpression struct {
 	Name      lexer.Token
 	Recursive bool
+	Public    bool
 	Signature TypeNode
 	Function  *FunctionLiteralExpression
 	Position  lexer.Token
@@ -196,6 +297,99 @@ func (f *FunctionDeclarationExpression) NodeType() string {
 	return "FunctionDeclarationExpression"
 }
 
+type ConstructorDeclaration struct {
+	Name     lexer.Token
+	Fields   []Parameter
+	Public   bool
+	Position lexer.Token
+}
+
+type ClassDeclarationExpression struct {
+	Name           lexer.Token
+	TypeParameters []lexer.Token
+	Constructors   []ConstructorDeclaration
+	Public         bool
+	Position       lexer.Token
+}
+
+func (c *ClassDeclarationExpression) exprNode() {}
+func (c *ClassDeclarationExpression) NodeType() string {
+	return "ClassDeclarationExpression"
+}
+
+type RecordDeclarationExpression struct {
+	Name           lexer.Token
+	TypeParameters []lexer.Token
+	Fields         []Parameter
+	Public         bool
+	Position       lexer.Token
+}
+
+func (r *RecordDeclarationExpression) exprNode() {}
+func (r *RecordDeclarationExpression) NodeType() string {
+	return "RecordDeclarationExpression"
+}
+
+// TypeAliasDeclarationExpression is `type Name[T] = type`. The alias
+// stands for its type wherever it is used.
+type TypeAliasDeclarationExpression struct {
+	Name           lexer.Token
+	TypeParameters []lexer.Token
+	Type           TypeNode
+	Public         bool
+	Position       lexer.Token
+}
+
+func (t *TypeAliasDeclarationExpression) exprNode() {}
+func (t *TypeAliasDeclarationExpression) NodeType() string {
+	return "TypeAliasDeclarationExpression"
+}
+
+// NewtypeDeclarationExpression is `newtype Name[T] = type`, a type distinct
+// from the one it wraps but represented exactly like it. Name is also the
+// constructor that wraps a value and the pattern that unwraps one.
+type NewtypeDeclarationExpression struct {
+	Name           lexer.Token
+	TypeParameters []lexer.Token
+	Type           TypeNode
+	Public         bool
+	Position       lexer.Token
+}
+
+func (n *NewtypeDeclarationExpression) exprNode() {}
+func (n *NewtypeDeclarationExpression) NodeType() string {
+	return "NewtypeDeclarationExpression"
+}
+
+type RecordField struct {
+	Name  lexer.Token
+	Value Expression
+}
+
+// RecordExpression is a record literal, or a functional update when Base
+// is set: `{ base with field: value }`.
+type RecordExpression struct {
+	Base     Expression
+	Fields   []RecordField
+	Position lexer.Token
+}
+
+func (r *RecordExpression) exprNode() {}
+func (r *RecordExpression) NodeType() string {
+	return "RecordExpression"
+}
+
+type FieldAccessExpression struct {
+	Target   Expression
+	Field    lexer.Token
+	Position lexer.Token
+}
+
+func (f *FieldAccessExpression) exprNode() {}
+func (f *FieldAccessExpression) NodeType() string {
+	return "FieldAccessExpression"
+}
+
 type BlockExpression struct {
 	Expressions []Expression
 	Position    lexer.Token
@@ -257,8 +451,12 @@ type NilPattern struct {
 func (n *NilPattern) patternNode()     {}
 func (n *NilPattern) NodeType() string { return "NilPattern" }
 
+// ListPattern matches a list of exactly len(Elements) elements, or at
+// least that many when Rest is set. Rest is a WildcardPattern for a bare
+// `...` and an IdentifierPattern for `...name`.
 type ListPattern struct {
 	Elements []Pattern
+	Rest     Pattern
 	Position lexer.Token
 }
 
@@ -267,6 +465,66 @@ func (l *ListPattern) NodeType() string {
 	return "ListPattern"
 }
 
+type TuplePattern struct {
+	Elements []Pattern
+	Position lexer.Token
+}
+
+func (t *TuplePattern) patternNode() {}
+func (t *TuplePattern) NodeType() string {
+	return "TuplePattern"
+}
+
+type RecordFieldPattern struct {
+	Name    lexer.Token
+	Pattern Pattern
+}
+
+// RecordPattern matches the listed fields of a record; fields that are
+// not mentioned match anything.
+type RecordPattern struct {
+	Fields   []RecordFieldPattern
+	Position lexer.Token
+}
+
+func (r *RecordPattern) patternNode() {}
+func (r *RecordPattern) NodeType() string {
+	return "RecordPattern"
+}
+
+type ConstructorPattern struct {
+	Class     string
+	Name      string
+	Arguments []Pattern
+	Position  lexer.Token
+}
+
+func (c *ConstructorPattern) patternNode() {}
+func (c *ConstructorPattern) NodeType() string {
+	return "ConstructorPattern"
+}
+
+type AsPattern struct {
+	Name     string
+	Pattern  Pattern
+	Position lexer.Token
+}
+
+func (a *AsPattern) patternNode() {}
+func (a *AsPattern) NodeType() string {
+	return "AsPattern"
+}
+
+type OrPattern struct {
+	Alternatives []Pattern
+	Position     lexer.Token
+}
+
+func (o *OrPattern) patternNode() {}
+func (o *OrPattern) NodeType() string {
+	return "OrPattern"
+}
+
 type MatchArm struct {
 	Pattern  Pattern
 	Guard    Expression
@@ -317,6 +575,17 @@ func (s *SimpleType) NodeType() string {
 	return "SimpleType"
 }
 
+type GenericType struct {
+	Name      string
+	Arguments []TypeNode
+	Position  lexer.Token
+}
+
+func (g *GenericType) typeNode() {}
+func (g *GenericType) NodeType() string {
+	return "GenericType"
+}
+
 type ListType struct {
 	Element  TypeNode
 	Position lexer.Token
@@ -327,6 +596,28 @@ func (l *ListType) NodeType() string {
 	return "ListType"
 }
 
+type TupleType struct {
+	Elements []TypeNode
+	Position lexer.Token
+}
+
+func (t *TupleType) typeNode() {}
+func (t *TupleType) NodeType() string {
+	return "TupleType"
+}
+
+// ForallType quantifies Type over Variables, as in `forall T. fn(T) -> T`.
+type ForallType struct {
+	Variables []lexer.Token
+	Type      TypeNode
+	Position  lexer.Token
+}
+
+func (f *ForallType) typeNode() {}
+func (f *ForallType) NodeType() string {
+	return "ForallType"
+}
+
 type FunctionType struct {
 	Parameters []TypeNode
 	Return     TypeNode
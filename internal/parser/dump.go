@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"lunno/internal/lexer"
 	"strings"
 )
 
@@ -41,10 +42,56 @@ func dumpPattern(p Pattern, indent string, last bool) string {
 		line, next := node(indent, last, "ListPattern")
 		var out strings.Builder
 		out.WriteString(line)
+		for i, e := range n.Elements {
+			out.WriteString(dumpPattern(e, next, i == len(n.Elements)-1 && n.Rest == nil))
+		}
+		if n.Rest != nil {
+			rLine, rNext := node(next, true, "Rest")
+			out.WriteString(rLine)
+			out.WriteString(dumpPattern(n.Rest, rNext, true))
+		}
+		return out.String()
+	case *TuplePattern:
+		line, next := node(indent, last, "TuplePattern")
+		var out strings.Builder
+		out.WriteString(line)
 		for i, e := range n.Elements {
 			out.WriteString(dumpPattern(e, next, i == len(n.Elements)-1))
 		}
 		return out.String()
+	case *RecordPattern:
+		line, next := node(indent, last, "RecordPattern")
+		var out strings.Builder
+		out.WriteString(line)
+		for i, f := range n.Fields {
+			fLine, fNext := node(next, i == len(n.Fields)-1, "Field "+f.Name.Lexeme)
+			out.WriteString(fLine)
+			out.WriteString(dumpPattern(f.Pattern, fNext, true))
+		}
+		return out.String()
+	case *ConstructorPattern:
+		name := n.Name
+		if n.Class != "" {
+			name = n.Class + "." + n.Name
+		}
+		line, next := node(indent, last, "ConstructorPattern "+name)
+		var out strings.Builder
+		out.WriteString(line)
+		for i, a := range n.Arguments {
+			out.WriteString(dumpPattern(a, next, i == len(n.Arguments)-1))
+		}
+		return out.String()
+	case *AsPattern:
+		line, next := node(indent, last, "AsPattern "+n.Name)
+		return line + dumpPattern(n.Pattern, next, true)
+	case *OrPattern:
+		line, next := node(indent, last, "OrPattern")
+		var out strings.Builder
+		out.WriteString(line)
+		for i, a := range n.Alternatives {
+			out.WriteString(dumpPattern(a, next, i == len(n.Alternatives)-1))
+		}
+		return out.String()
 	default:
 		line, _ := node(indent, last, fmt.Sprintf("<unknown pattern %T>", n))
 		return line
@@ -56,6 +103,9 @@ func dumpExpr(expr Expression, indent string, last bool) string {
 	case *Identifier:
 		line, _ := node(indent, last, "Identifier "+n.Name)
 		return line
+	case *HoleExpression:
+		line, _ := node(indent, last, "Hole "+n.Label())
+		return line
 	case *IntegerLiteral:
 		line, _ := node(indent, last, fmt.Sprintf("IntegerLiteral %d", n.Value))
 		return line
@@ -83,6 +133,14 @@ func dumpExpr(expr Expression, indent string, last bool) string {
 			out.WriteString(dumpExpr(e, next, i == len(n.Elements)-1))
 		}
 		return out.String()
+	case *TupleExpression:
+		line, next := node(indent, last, "TupleExpression")
+		var out strings.Builder
+		out.WriteString(line)
+		for i, e := range n.Elements {
+			out.WriteString(dumpExpr(e, next, i == len(n.Elements)-1))
+		}
+		return out.String()
 	case *IndexExpression:
 		line, next := node(indent, last, "IndexExpression")
 		var out strings.Builder
@@ -119,7 +177,7 @@ func dumpExpr(expr Expression, indent string, last bool) string {
 		return out.String()
 	case *VariableDeclarationExpression:
 		line, next := node(indent, last,
-			fmt.Sprintf("VariableDeclaration name=%s rec=%t", n.Name.Lexeme, n.Recursive))
+			fmt.Sprintf("VariableDeclaration name=%s rec=%t pub=%t", n.Name.Lexeme, n.Recursive, n.Public))
 		var out strings.Builder
 		out.WriteString(line)
 		if n.Type != nil {
@@ -131,9 +189,38 @@ func dumpExpr(expr Expression, indent string, last bool) string {
 		out.WriteString(vLine)
 		out.WriteString(dumpExpr(n.Value, vNext, true))
 		return out.String()
+	case *DestructuringDeclarationExpression:
+		line, next := node(indent, last, "DestructuringDeclaration")
+		var out strings.Builder
+		out.WriteString(line)
+		pLine, pNext := node(next, false, "Pattern")
+		out.WriteString(pLine)
+		out.WriteString(dumpPattern(n.Pattern, pNext, true))
+		if n.Type != nil {
+			tLine, tNext := node(next, false, "Type")
+			out.WriteString(tLine)
+			out.WriteString(dumpType(n.Type, tNext, true))
+		}
+		vLine, vNext := node(next, n.Else == nil, "Value")
+		out.WriteString(vLine)
+		out.WriteString(dumpExpr(n.Value, vNext, true))
+		if n.Else != nil {
+			eLine, eNext := node(next, true, "Else")
+			out.WriteString(eLine)
+			out.WriteString(dumpExpr(n.Else, eNext, true))
+		}
+		return out.String()
+	case *RecursiveGroupExpression:
+		line, next := node(indent, last, "RecursiveGroup")
+		var out strings.Builder
+		out.WriteString(line)
+		for i, d := range n.Declarations {
+			out.WriteString(dumpExpr(d, next, i == len(n.Declarations)-1))
+		}
+		return out.String()
 	case *FunctionDeclarationExpression:
 		line, next := node(indent, last,
-			fmt.Sprintf("FunctionDeclaration name=%s rec=%t", n.Name.Lexeme, n.Recursive))
+			fmt.Sprintf("FunctionDeclaration name=%s rec=%t pub=%t", n.Name.Lexeme, n.Recursive, n.Public))
 		var out strings.Builder
 		out.WriteString(line)
 		if n.Signature != nil {
@@ -164,6 +251,69 @@ func dumpExpr(expr Expression, indent string, last bool) string {
 		out.WriteString(bLine)
 		out.WriteString(dumpExpr(n.Body, bNext, true))
 		return out.String()
+	case *ClassDeclarationExpression:
+		label := fmt.Sprintf("ClassDeclaration name=%s pub=%t", n.Name.Lexeme, n.Public)
+		line, next := node(indent, last, label+dumpTypeParameters(n.TypeParameters))
+		var out strings.Builder
+		out.WriteString(line)
+		for i, c := range n.Constructors {
+			cLine, cNext := node(next, i == len(n.Constructors)-1,
+				fmt.Sprintf("Constructor %s pub=%t", c.Name.Lexeme, c.Public))
+			out.WriteString(cLine)
+			for j, f := range c.Fields {
+				label := "Field"
+				if f.Name.Lexeme != "" {
+					label += " " + f.Name.Lexeme
+				}
+				fLine, fNext := node(cNext, j == len(c.Fields)-1, label)
+				out.WriteString(fLine)
+				out.WriteString(dumpType(f.Type, fNext, true))
+			}
+		}
+		return out.String()
+	case *RecordDeclarationExpression:
+		label := fmt.Sprintf("RecordDeclaration name=%s pub=%t", n.Name.Lexeme, n.Public)
+		line, next := node(indent, last, label+dumpTypeParameters(n.TypeParameters))
+		var out strings.Builder
+		out.WriteString(line)
+		for i, f := range n.Fields {
+			fLine, fNext := node(next, i == len(n.Fields)-1, "Field "+f.Name.Lexeme)
+			out.WriteString(fLine)
+			out.WriteString(dumpType(f.Type, fNext, true))
+		}
+		return out.String()
+	case *TypeAliasDeclarationExpression:
+		label := fmt.Sprintf("TypeAliasDeclaration name=%s pub=%t", n.Name.Lexeme, n.Public)
+		line, next := node(indent, last, label+dumpTypeParameters(n.TypeParameters))
+		return line + dumpType(n.Type, next, true)
+	case *NewtypeDeclarationExpression:
+		label := fmt.Sprintf("NewtypeDeclaration name=%s pub=%t", n.Name.Lexeme, n.Public)
+		line, next := node(indent, last, label+dumpTypeParameters(n.TypeParameters))
+		return line + dumpType(n.Type, next, true)
+	case *RecordExpression:
+		line, next := node(indent, last, "RecordExpression")
+		var out strings.Builder
+		out.WriteString(line)
+		if n.Base != nil {
+			bLine, bNext := node(next, false, "Base")
+			out.WriteString(bLine)
+			out.WriteString(dumpExpr(n.Base, bNext, true))
+		}
+		for i, f := range n.Fields {
+			fLine, fNext := node(next, i == len(n.Fields)-1, "Field "+f.Name.Lexeme)
+			out.WriteString(fLine)
+			out.WriteString(dumpExpr(f.Value, fNext, true))
+		}
+		return out.String()
+	case *FieldAccessExpression:
+		line, next := node(indent, last, "FieldAccess "+n.Field.Lexeme)
+		return line + dumpExpr(n.Target, next, true)
+	case *PropagateExpression:
+		line, next := node(indent, last, "PropagateExpression")
+		return line + dumpExpr(n.Target, next, true)
+	case *LazyExpression:
+		line, next := node(indent, last, "LazyExpression")
+		return line + dumpExpr(n.Body, next, true)
 	case *BlockExpression:
 		line, next := node(indent, last, "BlockExpression")
 		var out strings.Builder
@@ -188,6 +338,17 @@ func dumpExpr(expr Expression, indent string, last bool) string {
 			out.WriteString(dumpExpr(n.Else, eNext, true))
 		}
 		return out.String()
+	case *TryExpression:
+		line, next := node(indent, last, "TryExpression")
+		var out strings.Builder
+		out.WriteString(line)
+		bLine, bNext := node(next, false, "Body")
+		out.WriteString(bLine)
+		out.WriteString(dumpExpr(n.Body, bNext, true))
+		rLine, rNext := node(next, true, "Recover "+n.Binding.Lexeme)
+		out.WriteString(rLine)
+		out.WriteString(dumpExpr(n.Handler, rNext, true))
+		return out.String()
 	case *MatchExpression:
 		line, next := node(indent, last, "MatchExpression")
 		var out strings.Builder
@@ -244,14 +405,48 @@ func dumpExpr(expr Expression, indent string, last bool) string {
 	}
 }
 
+func dumpTypeParameters(params []lexer.Token) string {
+	if len(params) == 0 {
+		return ""
+	}
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.Lexeme
+	}
+	return " params=[" + strings.Join(names, ", ") + "]"
+}
+
 func dumpType(t TypeNode, indent string, last bool) string {
 	switch n := t.(type) {
 	case *SimpleType:
 		line, _ := node(indent, last, "Type "+n.Name)
 		return line
+	case *GenericType:
+		line, next := node(indent, last, "GenericType "+n.Name)
+		var out strings.Builder
+		out.WriteString(line)
+		for i, a := range n.Arguments {
+			out.WriteString(dumpType(a, next, i == len(n.Arguments)-1))
+		}
+		return out.String()
+	case *TupleType:
+		line, next := node(indent, last, "TupleType")
+		var out strings.Builder
+		out.WriteString(line)
+		for i, e := range n.Elements {
+			out.WriteString(dumpType(e, next, i == len(n.Elements)-1))
+		}
+		return out.String()
 	case *ListType:
 		line, next := node(indent, last, "ListType")
 		return line + dumpType(n.Element, next, true)
+	case *ForallType:
+		names := make([]string, len(n.Variables))
+		for i, v := range n.Variables {
+			names[i] = v.Lexeme
+		}
+		line, next := node(indent, last, "ForallType "+strings.Join(names, " "))
+		return line + dumpType(n.Type, next, true)
 	case *FunctionType:
 		line, next := node(indent, last, "FunctionType")
 		var out strings.Builder
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"lunno/internal/lexer"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 type Parser struct {
@@ -26,7 +28,7 @@ func ParseProgram(tokens []lexer.Token, lx *lexer.Lexer) (*Program, []string) {
 	parser := NewParser(tokens, lx)
 	program := &Program{Expressions: []Expression{}}
 	for parser.cur().Type != lexer.EndOfFile {
-		expression := parser.parseExpression(0)
+		expression := parser.parseTopLevel()
 		if expression == nil {
 			parser.advance()
 			continue
@@ -36,6 +38,60 @@ func ParseProgram(tokens []lexer.Token, lx *lexer.Lexer) (*Program, []string) {
 	return program, parser.errors
 }
 
+func (parser *Parser) parseTopLevel() Expression {
+	switch parser.cur().Type {
+	case lexer.KwClass:
+		return parser.parseClassDeclaration()
+	case lexer.KwRecord:
+		return parser.parseRecordDeclaration()
+	case lexer.KwType, lexer.KwNewtype:
+		return parser.parseTypeDeclaration()
+	case lexer.KwPub:
+	default:
+		return parser.parseExpression(0)
+	}
+	parser.advance()
+	var decl Expression
+	switch parser.cur().Type {
+	case lexer.KwLet:
+		decl = parser.parseLetExpression()
+	case lexer.KwClass:
+		decl = parser.parseClassDeclaration()
+	case lexer.KwRecord:
+		decl = parser.parseRecordDeclaration()
+	case lexer.KwType, lexer.KwNewtype:
+		decl = parser.parseTypeDeclaration()
+	default:
+		e := parser.error(parser.cur(), "expected declaration after 'pub'")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	switch d := decl.(type) {
+	case *VariableDeclarationExpression:
+		d.Public = true
+	case *FunctionDeclarationExpression:
+		d.Public = true
+	case *ClassDeclarationExpression:
+		d.Public = true
+	case *RecordDeclarationExpression:
+		d.Public = true
+	case *RecursiveGroupExpression:
+		for _, member := range d.Declarations {
+			switch m := member.(type) {
+			case *VariableDeclarationExpression:
+				m.Public = true
+			case *FunctionDeclarationExpression:
+				m.Public = true
+			}
+		}
+	case *TypeAliasDeclarationExpression:
+		d.Public = true
+	case *NewtypeDeclarationExpression:
+		d.Public = true
+	}
+	return decl
+}
+
 func (parser *Parser) parseExpression(minPrecedence int) Expression {
 	token := parser.cur()
 	if token.Type == lexer.KwLet {
@@ -65,6 +121,23 @@ func (parser *Parser) parseExpression(minPrecedence int) Expression {
 			Position: op,
 		}
 	}
+	// `r := v` binds looser than any operator and groups to the right, so
+	// `r := xs |> f` stores the whole pipeline.
+	if minPrecedence == 0 && parser.cur().Type == lexer.ColonAssign {
+		op := parser.advance()
+		right := parser.parseExpression(0)
+		if right == nil {
+			err := parser.error(op, "expected expression on right-hand side of operator")
+			parser.errors = append(parser.errors, err.Error())
+			return left
+		}
+		left = &InfixExpression{
+			Left:     left,
+			Operator: op,
+			Right:    right,
+			Position: op,
+		}
+	}
 	return left
 }
 
@@ -135,15 +208,28 @@ func (parser *Parser) parsePrimary() Expression {
 			Position: token}
 	case lexer.Char:
 		token := parser.expect(lexer.Char)
+		value, ok := unquote(token.Lexeme)
+		if !ok || len(value) != 1 {
+			e := parser.error(token, "invalid character literal")
+			parser.errors = append(parser.errors, e.Error())
+			return nil
+		}
 		expr = &CharacterLiteral{
-			Value:    token.Lexeme[0],
+			Value:    value[0],
 			Raw:      token.Lexeme,
 			Position: token,
 		}
 	case lexer.String:
 		token := parser.expect(lexer.String)
+		value, ok := unquote(token.Lexeme)
+		if !ok {
+			e := parser.error(token, "invalid string literal")
+			parser.errors = append(parser.errors, e.Error())
+			return nil
+		}
 		expr = &StringLiteral{
-			Value:    token.Lexeme,
+			Value:    value,
+			Raw:      token.Lexeme,
 			Position: token,
 		}
 	case lexer.Bool:
@@ -162,12 +248,45 @@ func (parser *Parser) parsePrimary() Expression {
 			}
 		}
 		expr = parser.parseExpression(0)
+		if parser.cur().Type == lexer.Comma {
+			elements := []Expression{expr}
+			for parser.cur().Type == lexer.Comma {
+				parser.advance()
+				el := parser.parseExpression(0)
+				if el == nil {
+					e := parser.error(parser.cur(), "invalid expression in tuple")
+					parser.errors = append(parser.errors, e.Error())
+					return nil
+				}
+				elements = append(elements, el)
+			}
+			expr = &TupleExpression{
+				Elements: elements,
+				Position: token,
+			}
+		}
 		parser.expect(lexer.RightParen)
 	case lexer.Identifier:
 		parser.advance()
 		expr = &Identifier{
 			Name:     token.Lexeme,
 			Position: token}
+	case lexer.Question:
+		if !parser.startsHole() {
+			e := parser.error(token, "expected a name right after '?' in a hole")
+			parser.errors = append(parser.errors, e.Error())
+			parser.advance()
+			return nil
+		}
+		parser.advance()
+		name := parser.advance()
+		expr = &HoleExpression{
+			Name:     name.Lexeme,
+			Position: token}
+	case lexer.Underscore:
+		parser.advance()
+		expr = &HoleExpression{
+			Position: token}
 	case lexer.KwUnit:
 		token := parser.expect(lexer.KwUnit)
 		expr = &UnitLiteral{
@@ -179,12 +298,66 @@ func (parser *Parser) parsePrimary() Expression {
 		expr = parser.parseIfExpression()
 	case lexer.KwMatch:
 		expr = parser.parseMatchExpression()
+	case lexer.KwTry:
+		expr = parser.parseTryExpression()
+	case lexer.KwLazy:
+		parser.advance()
+		var body Expression
+		if parser.cur().Type == lexer.LeftBrace {
+			body = parser.parseBlock(parser.cur(), "lazy")
+		} else {
+			body = parser.parseExpression(0)
+		}
+		if body == nil {
+			e := parser.error(token, "expected expression after 'lazy'")
+			parser.errors = append(parser.errors, e.Error())
+			return nil
+		}
+		return &LazyExpression{
+			Body:     body,
+			Position: token,
+		}
 	case lexer.KwImport:
 		parser.advance()
 		mod := parser.expect(lexer.Identifier)
 		expr = &ImportExpression{
 			Module:   mod.Lexeme,
 			Position: token}
+	case lexer.KwPub:
+		parser.advance()
+		e := parser.error(token, "'pub' is only allowed on top-level declarations")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	case lexer.KwClass:
+		parser.advance()
+		e := parser.error(token, "'class' declarations are only allowed at the top level")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	case lexer.KwRecord:
+		parser.advance()
+		e := parser.error(token, "'record' declarations are only allowed at the top level")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	case lexer.KwType, lexer.KwNewtype:
+		parser.advance()
+		e := parser.error(token, fmt.Sprintf("'%s' declarations are only allowed at the top level", token.Lexeme))
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	case lexer.LeftBrace:
+		expr = parser.parseRecordExpression()
+	case lexer.Minus, lexer.Bang, lexer.KwNot:
+		parser.advance()
+		right := parser.parseExpression(lexer.PrefixPrecedence)
+		if right == nil {
+			e := parser.error(token, fmt.Sprintf("expected expression after %q", token.Lexeme))
+			parser.errors = append(parser.errors, e.Error())
+			return nil
+		}
+		return &PrefixExpression{
+			Operator: token,
+			Right:    right,
+			Position: token,
+		}
 	case lexer.LeftBracket:
 		parser.advance()
 		var elements []Expression
@@ -203,6 +376,17 @@ func (parser *Parser) parsePrimary() Expression {
 func (parser *Parser) parsePostfix(expr Expression) Expression {
 	for {
 		switch parser.cur().Type {
+		case lexer.Dot:
+			dotToken := parser.advance()
+			field := parser.expect(lexer.Identifier)
+			if field.Type != lexer.Identifier {
+				return expr
+			}
+			expr = &FieldAccessExpression{
+				Target:   expr,
+				Field:    field,
+				Position: dotToken,
+			}
 		case lexer.LeftParen:
 			callToken := parser.cur()
 			parser.advance()
@@ -255,12 +439,27 @@ func (parser *Parser) parsePostfix(expr Expression) Expression {
 					Position: startToken,
 				}
 			}
+		case lexer.Question:
+			if parser.startsHole() {
+				return expr
+			}
+			expr = &PropagateExpression{
+				Target:   expr,
+				Position: parser.advance(),
+			}
 		default:
 			return expr
 		}
 	}
 }
 
+// startsHole reports whether the current `?` begins a hole. A hole's name
+// follows the `?` directly; any other `?` propagates an error.
+func (parser *Parser) startsHole() bool {
+	token, name := parser.cur(), parser.next()
+	return name.Type == lexer.Identifier && name.Line == token.Line && name.Column == token.Column+1
+}
+
 func (parser *Parser) parseSliceExpr() Expression {
 	switch parser.cur().Type {
 	case lexer.RightBracket, lexer.Colon:
@@ -278,6 +477,35 @@ func (parser *Parser) parseLetExpression() Expression {
 		recursive = true
 		parser.advance()
 	}
+	if !recursive && parser.startsLetPattern() {
+		return parser.parseDestructuringDeclaration(letToken)
+	}
+	decl := parser.parseLetBinding(letToken, recursive)
+	if decl == nil || parser.cur().Type != lexer.KwAnd {
+		return decl
+	}
+	if !recursive {
+		e := parser.error(parser.cur(), "'and' can only join 'let rec' declarations")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	group := &RecursiveGroupExpression{
+		Declarations: []Expression{decl},
+		Position:     letToken,
+	}
+	for parser.cur().Type == lexer.KwAnd {
+		decl := parser.parseLetBinding(parser.advance(), true)
+		if decl == nil {
+			return nil
+		}
+		group.Declarations = append(group.Declarations, decl)
+	}
+	return group
+}
+
+// parseLetBinding parses `name: type = value` after 'let', 'let rec' or
+// 'and'.
+func (parser *Parser) parseLetBinding(letToken lexer.Token, recursive bool) Expression {
 	name := parser.expect(lexer.Identifier)
 	if name.Type != lexer.Identifier {
 		e := parser.error(name, "expected identifier after 'let'")
@@ -328,6 +556,298 @@ func (parser *Parser) parseLetExpression() Expression {
 	}
 }
 
+// startsLetPattern reports whether a let binds a pattern rather than a
+// name. A name followed by '(' or '.' starts a constructor pattern.
+func (parser *Parser) startsLetPattern() bool {
+	switch parser.cur().Type {
+	case lexer.LeftParen, lexer.LeftBracket, lexer.LeftBrace, lexer.Underscore:
+		return true
+	case lexer.Identifier:
+		next := parser.next().Type
+		return next == lexer.LeftParen || next == lexer.Dot
+	}
+	return false
+}
+
+func (parser *Parser) parseDestructuringDeclaration(letToken lexer.Token) Expression {
+	pattern := parser.parsePattern()
+	if pattern == nil {
+		return nil
+	}
+	var typ TypeNode
+	if parser.cur().Type == lexer.Colon {
+		parser.advance()
+		typ = parser.parseType()
+		if typ == nil {
+			return nil
+		}
+	}
+	if parser.cur().Type != lexer.Assign {
+		e := parser.error(parser.cur(), "expected '=' after pattern in let declaration")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	parser.advance()
+	value := parser.parseExpression(0)
+	if value == nil {
+		e := parser.error(parser.cur(), "expected value in let declaration")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	var otherwise Expression
+	if parser.cur().Type == lexer.KwElse {
+		parser.advance()
+		otherwise = parser.parseBlock(parser.cur(), "else")
+		if otherwise == nil {
+			return nil
+		}
+	}
+	return &DestructuringDeclarationExpression{
+		Pattern:  pattern,
+		Type:     typ,
+		Value:    value,
+		Else:     otherwise,
+		Position: letToken,
+	}
+}
+
+func (parser *Parser) parseClassDeclaration() Expression {
+	classToken := parser.advance()
+	name := parser.expect(lexer.Identifier)
+	if name.Type != lexer.Identifier {
+		e := parser.error(name, "expected class name after 'class'")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	params, ok := parser.parseTypeParameters()
+	if !ok {
+		return nil
+	}
+	if parser.cur().Type != lexer.LeftBrace {
+		e := parser.error(parser.cur(), "expected '{' to start class body")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	parser.advance()
+	var constructors []ConstructorDeclaration
+	for parser.cur().Type != lexer.RightBrace && parser.cur().Type != lexer.EndOfFile {
+		start := parser.cur()
+		public := false
+		if start.Type == lexer.KwPub {
+			public = true
+			parser.advance()
+		}
+		ctorName := parser.expect(lexer.Identifier)
+		if ctorName.Type != lexer.Identifier {
+			e := parser.error(ctorName, "expected constructor name in class body")
+			parser.errors = append(parser.errors, e.Error())
+			return nil
+		}
+		var fields []Parameter
+		if parser.cur().Type == lexer.LeftParen {
+			parser.advance()
+			for parser.cur().Type != lexer.RightParen && parser.cur().Type != lexer.EndOfFile {
+				field := parser.cur()
+				var fieldName lexer.Token
+				if field.Type == lexer.Identifier && parser.next().Type == lexer.Colon {
+					fieldName = parser.advance()
+					parser.advance()
+				}
+				fieldType := parser.parseType()
+				if fieldType == nil {
+					return nil
+				}
+				fields = append(fields, Parameter{
+					Name:     fieldName,
+					Type:     fieldType,
+					Position: field,
+				})
+				if parser.cur().Type == lexer.Comma {
+					parser.advance()
+				} else {
+					break
+				}
+			}
+			parser.expect(lexer.RightParen)
+		}
+		constructors = append(constructors, ConstructorDeclaration{
+			Name:     ctorName,
+			Fields:   fields,
+			Public:   public,
+			Position: start,
+		})
+		if parser.cur().Type == lexer.Comma {
+			parser.advance()
+		}
+	}
+	parser.expect(lexer.RightBrace)
+	return &ClassDeclarationExpression{
+		Name:           name,
+		TypeParameters: params,
+		Constructors:   constructors,
+		Position:       classToken,
+	}
+}
+
+func (parser *Parser) parseTypeParameters() ([]lexer.Token, bool) {
+	var params []lexer.Token
+	if parser.cur().Type != lexer.LeftBracket {
+		return nil, true
+	}
+	parser.advance()
+	for parser.cur().Type != lexer.RightBracket && parser.cur().Type != lexer.EndOfFile {
+		param := parser.expect(lexer.Identifier)
+		if param.Type != lexer.Identifier {
+			return nil, false
+		}
+		params = append(params, param)
+		if parser.cur().Type == lexer.Comma {
+			parser.advance()
+		} else {
+			break
+		}
+	}
+	parser.expect(lexer.RightBracket)
+	return params, true
+}
+
+func (parser *Parser) parseRecordDeclaration() Expression {
+	recordToken := parser.advance()
+	name := parser.expect(lexer.Identifier)
+	if name.Type != lexer.Identifier {
+		e := parser.error(name, "expected record name after 'record'")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	params, ok := parser.parseTypeParameters()
+	if !ok {
+		return nil
+	}
+	if parser.cur().Type != lexer.LeftBrace {
+		e := parser.error(parser.cur(), "expected '{' to start record body")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	parser.advance()
+	var fields []Parameter
+	for parser.cur().Type != lexer.RightBrace && parser.cur().Type != lexer.EndOfFile {
+		fieldName := parser.expect(lexer.Identifier)
+		if fieldName.Type != lexer.Identifier {
+			e := parser.error(fieldName, "expected field name in record body")
+			parser.errors = append(parser.errors, e.Error())
+			return nil
+		}
+		parser.expect(lexer.Colon)
+		fieldType := parser.parseType()
+		if fieldType == nil {
+			return nil
+		}
+		fields = append(fields, Parameter{
+			Name:     fieldName,
+			Type:     fieldType,
+			Position: fieldName,
+		})
+		if parser.cur().Type == lexer.Comma {
+			parser.advance()
+		}
+	}
+	parser.expect(lexer.RightBrace)
+	return &RecordDeclarationExpression{
+		Name:           name,
+		TypeParameters: params,
+		Fields:         fields,
+		Position:       recordToken,
+	}
+}
+
+// parseTypeDeclaration parses `type Name[T] = type` and
+// `newtype Name[T] = type`.
+func (parser *Parser) parseTypeDeclaration() Expression {
+	keyword := parser.advance()
+	name := parser.expect(lexer.Identifier)
+	if name.Type != lexer.Identifier {
+		e := parser.error(name, fmt.Sprintf("expected type name after '%s'", keyword.Lexeme))
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	params, ok := parser.parseTypeParameters()
+	if !ok {
+		return nil
+	}
+	if parser.cur().Type != lexer.Assign {
+		e := parser.error(parser.cur(), fmt.Sprintf("expected '=' after %s name", keyword.Lexeme))
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	parser.advance()
+	typ := parser.parseType()
+	if typ == nil {
+		return nil
+	}
+	if keyword.Type == lexer.KwNewtype {
+		return &NewtypeDeclarationExpression{
+			Name:           name,
+			TypeParameters: params,
+			Type:           typ,
+			Position:       keyword,
+		}
+	}
+	return &TypeAliasDeclarationExpression{
+		Name:           name,
+		TypeParameters: params,
+		Type:           typ,
+		Position:       keyword,
+	}
+}
+
+func (parser *Parser) parseRecordExpression() Expression {
+	start := parser.advance()
+	record := &RecordExpression{
+		Position: start,
+	}
+	if parser.cur().Type != lexer.Identifier || parser.next().Type != lexer.Colon {
+		record.Base = parser.parseExpression(0)
+		if record.Base == nil {
+			return nil
+		}
+		if parser.cur().Type != lexer.KwWith {
+			e := parser.error(parser.cur(), "expected 'with' after record in update expression")
+			parser.errors = append(parser.errors, e.Error())
+			return nil
+		}
+		parser.advance()
+	}
+	for parser.cur().Type != lexer.RightBrace && parser.cur().Type != lexer.EndOfFile {
+		name := parser.expect(lexer.Identifier)
+		if name.Type != lexer.Identifier {
+			return nil
+		}
+		parser.expect(lexer.Colon)
+		value := parser.parseExpression(0)
+		if value == nil {
+			e := parser.error(parser.cur(), "expected value for record field")
+			parser.errors = append(parser.errors, e.Error())
+			return nil
+		}
+		record.Fields = append(record.Fields, RecordField{
+			Name:  name,
+			Value: value,
+		})
+		if parser.cur().Type == lexer.Comma {
+			parser.advance()
+		} else {
+			break
+		}
+	}
+	parser.expect(lexer.RightBrace)
+	if len(record.Fields) == 0 {
+		e := parser.error(start, "record expression needs at least one field")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	return record
+}
+
 func (parser *Parser) parseFunctionLiteral() Expression {
 	fnToken := parser.cur()
 	parser.advance()
@@ -435,6 +955,44 @@ func (parser *Parser) parseIfExpression() Expression {
 	}
 }
 
+func (parser *Parser) parseTryExpression() Expression {
+	tryToken := parser.cur()
+	parser.advance()
+	body := parser.parseBlock(tryToken, "try")
+	if body == nil {
+		return nil
+	}
+	if parser.cur().Type != lexer.KwRecover {
+		e := parser.error(parser.cur(), "expected 'recover' after try block")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	parser.advance()
+	binding := parser.cur()
+	if binding.Type != lexer.Identifier && binding.Type != lexer.Underscore {
+		e := parser.error(binding, "expected a name or '_' after 'recover'")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	parser.advance()
+	parser.expect(lexer.Arrow)
+	var handler Expression
+	if parser.cur().Type == lexer.LeftBrace {
+		handler = parser.parseBlock(parser.cur(), "recover")
+	} else {
+		handler = parser.parseExpression(0)
+	}
+	if handler == nil {
+		return nil
+	}
+	return &TryExpression{
+		Body:     body,
+		Binding:  binding,
+		Handler:  handler,
+		Position: tryToken,
+	}
+}
+
 func (parser *Parser) parseMatchExpression() Expression {
 	matchToken := parser.cur()
 	parser.advance()
@@ -447,7 +1005,7 @@ func (parser *Parser) parseMatchExpression() Expression {
 	var arms []MatchArm
 	for parser.cur().Type != lexer.RightBrace &&
 		parser.cur().Type != lexer.EndOfFile {
-		parser.expect(lexer.Pipe)
+		armToken := parser.expect(lexer.Pipe)
 		pat := parser.parsePattern()
 		if pat == nil {
 			return nil
@@ -466,7 +1024,7 @@ func (parser *Parser) parseMatchExpression() Expression {
 			Pattern:  pat,
 			Guard:    guard,
 			Body:     body,
-			Position: matchToken,
+			Position: armToken,
 		})
 	}
 	parser.expect(lexer.RightBrace)
@@ -478,6 +1036,27 @@ func (parser *Parser) parseMatchExpression() Expression {
 }
 
 func (parser *Parser) parsePattern() Pattern {
+	start := parser.cur()
+	first := parser.parsePatternAtom()
+	if first == nil || parser.cur().Type != lexer.Pipe {
+		return first
+	}
+	alternatives := []Pattern{first}
+	for parser.cur().Type == lexer.Pipe {
+		parser.advance()
+		alt := parser.parsePatternAtom()
+		if alt == nil {
+			return nil
+		}
+		alternatives = append(alternatives, alt)
+	}
+	return &OrPattern{
+		Alternatives: alternatives,
+		Position:     start,
+	}
+}
+
+func (parser *Parser) parsePatternAtom() Pattern {
 	token := parser.cur()
 	switch token.Type {
 	case lexer.Underscore:
@@ -486,11 +1065,7 @@ func (parser *Parser) parsePattern() Pattern {
 			Position: token,
 		}
 	case lexer.Identifier:
-		parser.advance()
-		return &IdentifierPattern{
-			Name:     token.Lexeme,
-			Position: token,
-		}
+		return parser.parseIdentifierPattern()
 	case lexer.KwNil:
 		parser.advance()
 		return &NilPattern{
@@ -498,12 +1073,33 @@ func (parser *Parser) parsePattern() Pattern {
 		}
 	case lexer.LeftBracket:
 		return parser.parseListPattern()
+	case lexer.LeftParen:
+		return parser.parseTuplePattern()
+	case lexer.LeftBrace:
+		return parser.parseRecordPattern()
 	case lexer.Int, lexer.Float, lexer.String, lexer.Char, lexer.Bool:
 		lit := parser.parsePrimary()
 		return &LiteralPattern{
 			Value:    lit,
 			Position: token,
 		}
+	case lexer.Minus:
+		parser.advance()
+		lit := parser.parsePrimary()
+		switch lit := lit.(type) {
+		case *IntegerLiteral:
+			lit.Value, lit.Raw = -lit.Value, "-"+lit.Raw
+		case *FloatLiteral:
+			lit.Value, lit.Raw = -lit.Value, "-"+lit.Raw
+		default:
+			e := parser.error(token, "expected a number after '-' in pattern")
+			parser.errors = append(parser.errors, e.Error())
+			return nil
+		}
+		return &LiteralPattern{
+			Value:    lit,
+			Position: token,
+		}
 	default:
 		e := parser.error(token, "invalid pattern")
 		parser.errors = append(parser.errors, e.Error())
@@ -511,12 +1107,152 @@ func (parser *Parser) parsePattern() Pattern {
 	}
 }
 
+func (parser *Parser) parseIdentifierPattern() Pattern {
+	token := parser.advance()
+	switch parser.cur().Type {
+	case lexer.At:
+		parser.advance()
+		inner := parser.parsePatternAtom()
+		if inner == nil {
+			return nil
+		}
+		return &AsPattern{
+			Name:     token.Lexeme,
+			Pattern:  inner,
+			Position: token,
+		}
+	case lexer.Dot:
+		parser.advance()
+		name := parser.expect(lexer.Identifier)
+		if name.Type != lexer.Identifier {
+			return nil
+		}
+		return parser.parseConstructorArguments(&ConstructorPattern{
+			Class:    token.Lexeme,
+			Name:     name.Lexeme,
+			Position: token,
+		})
+	case lexer.LeftParen:
+		return parser.parseConstructorArguments(&ConstructorPattern{
+			Name:     token.Lexeme,
+			Position: token,
+		})
+	}
+	if unicode.IsUpper(rune(token.Lexeme[0])) {
+		return &ConstructorPattern{
+			Name:     token.Lexeme,
+			Position: token,
+		}
+	}
+	return &IdentifierPattern{
+		Name:     token.Lexeme,
+		Position: token,
+	}
+}
+
+func (parser *Parser) parseConstructorArguments(pattern *ConstructorPattern) Pattern {
+	if parser.cur().Type != lexer.LeftParen {
+		return pattern
+	}
+	parser.advance()
+	for parser.cur().Type != lexer.RightParen &&
+		parser.cur().Type != lexer.EndOfFile {
+		p := parser.parsePattern()
+		if p == nil {
+			return nil
+		}
+		pattern.Arguments = append(pattern.Arguments, p)
+		if parser.cur().Type == lexer.Comma {
+			parser.advance()
+		} else {
+			break
+		}
+	}
+	parser.expect(lexer.RightParen)
+	return pattern
+}
+
+func (parser *Parser) parseTuplePattern() Pattern {
+	start := parser.advance()
+	first := parser.parsePattern()
+	if first == nil {
+		return nil
+	}
+	if parser.cur().Type != lexer.Comma {
+		parser.expect(lexer.RightParen)
+		return first
+	}
+	elems := []Pattern{first}
+	for parser.cur().Type == lexer.Comma {
+		parser.advance()
+		p := parser.parsePattern()
+		if p == nil {
+			return nil
+		}
+		elems = append(elems, p)
+	}
+	parser.expect(lexer.RightParen)
+	return &TuplePattern{
+		Elements: elems,
+		Position: start,
+	}
+}
+
+func (parser *Parser) parseRecordPattern() Pattern {
+	start := parser.advance()
+	pattern := &RecordPattern{
+		Position: start,
+	}
+	for parser.cur().Type != lexer.RightBrace &&
+		parser.cur().Type != lexer.EndOfFile {
+		name := parser.expect(lexer.Identifier)
+		if name.Type != lexer.Identifier {
+			return nil
+		}
+		var field Pattern = &IdentifierPattern{
+			Name:     name.Lexeme,
+			Position: name,
+		}
+		if parser.cur().Type == lexer.Colon {
+			parser.advance()
+			field = parser.parsePattern()
+			if field == nil {
+				return nil
+			}
+		}
+		pattern.Fields = append(pattern.Fields, RecordFieldPattern{
+			Name:    name,
+			Pattern: field,
+		})
+		if parser.cur().Type == lexer.Comma {
+			parser.advance()
+		} else {
+			break
+		}
+	}
+	parser.expect(lexer.RightBrace)
+	return pattern
+}
+
 func (parser *Parser) parseListPattern() Pattern {
 	start := parser.cur()
 	parser.advance()
 	var elems []Pattern
+	var rest Pattern
 	for parser.cur().Type != lexer.RightBracket &&
 		parser.cur().Type != lexer.EndOfFile {
+		if parser.cur().Type == lexer.Ellipsis {
+			rest = parser.parseRestPattern()
+			if rest == nil {
+				return nil
+			}
+			if parser.cur().Type == lexer.Comma {
+				e := parser.error(parser.cur(), "'...' must be the last element of a list pattern")
+				parser.errors = append(parser.errors, e.Error())
+				return nil
+			}
+			break
+		}
 		p := parser.parsePattern()
 		if p == nil {
 			return nil
@@ -531,10 +1267,33 @@ func (parser *Parser) parseListPattern() Pattern {
 	parser.expect(lexer.RightBracket)
 	return &ListPattern{
 		Elements: elems,
+		Rest:     rest,
 		Position: start,
 	}
 }
 
+func (parser *Parser) parseRestPattern() Pattern {
+	ellipsis := parser.advance()
+	switch parser.cur().Type {
+	case lexer.Identifier:
+		name := parser.advance()
+		return &IdentifierPattern{
+			Name:     name.Lexeme,
+			Position: name,
+		}
+	case lexer.Underscore, lexer.RightBracket:
+		if parser.cur().Type == lexer.Underscore {
+			parser.advance()
+		}
+		return &WildcardPattern{
+			Position: ellipsis,
+		}
+	}
+	e := parser.error(parser.cur(), "expected a name or ']' after '...' in list pattern")
+	parser.errors = append(parser.errors, e.Error())
+	return nil
+}
+
 func (parser *Parser) parseType() TypeNode {
 	token := parser.cur()
 	switch token.Type {
@@ -562,6 +1321,39 @@ func (parser *Parser) parseType() TypeNode {
 			Return:     returnType,
 			Position:   token,
 		}
+	case lexer.LeftParen:
+		parser.advance()
+		if parser.cur().Type == lexer.RightParen {
+			parser.advance()
+			return &SimpleType{
+				Name: "unit",
+				Pos:  token,
+			}
+		}
+		first := parser.parseType()
+		if first == nil {
+			return nil
+		}
+		if parser.cur().Type != lexer.Comma {
+			parser.expect(lexer.RightParen)
+			return first
+		}
+		elems := []TypeNode{first}
+		for parser.cur().Type == lexer.Comma {
+			parser.advance()
+			el := parser.parseType()
+			if el == nil {
+				return nil
+			}
+			elems = append(elems, el)
+		}
+		parser.expect(lexer.RightParen)
+		return &TupleType{
+			Elements: elems,
+			Position: token,
+		}
+	case lexer.KwForall:
+		return parser.parseForallType()
 	case lexer.LeftBracket:
 		parser.advance()
 		elemType := parser.parseType()
@@ -570,7 +1362,35 @@ func (parser *Parser) parseType() TypeNode {
 			Element:  elemType,
 			Position: token,
 		}
-	case lexer.Identifier, lexer.KwInt, lexer.KwFloat, lexer.KwBool, lexer.KwString, lexer.KwChar, lexer.KwUnit:
+	case lexer.Identifier:
+		parser.advance()
+		if parser.cur().Type != lexer.LeftBracket {
+			return &SimpleType{
+				Name: token.Lexeme,
+				Pos:  token,
+			}
+		}
+		parser.advance()
+		var args []TypeNode
+		for parser.cur().Type != lexer.RightBracket && parser.cur().Type != lexer.EndOfFile {
+			arg := parser.parseType()
+			if arg == nil {
+				return nil
+			}
+			args = append(args, arg)
+			if parser.cur().Type == lexer.Comma {
+				parser.advance()
+			} else {
+				break
+			}
+		}
+		parser.expect(lexer.RightBracket)
+		return &GenericType{
+			Name:      token.Lexeme,
+			Arguments: args,
+			Position:  token,
+		}
+	case lexer.KwInt, lexer.KwFloat, lexer.KwBool, lexer.KwString, lexer.KwChar, lexer.KwUnit:
 		parser.advance()
 		return &SimpleType{
 			Name: token.Lexeme,
@@ -583,6 +1403,31 @@ func (parser *Parser) parseType() TypeNode {
 	}
 }
 
+func (parser *Parser) parseForallType() TypeNode {
+	token := parser.cur()
+	parser.advance()
+	var vars []lexer.Token
+	for parser.cur().Type == lexer.Identifier {
+		vars = append(vars, parser.cur())
+		parser.advance()
+	}
+	if len(vars) == 0 {
+		e := parser.error(parser.cur(), "expected type variable after 'forall'")
+		parser.errors = append(parser.errors, e.Error())
+		return nil
+	}
+	parser.expect(lexer.Dot)
+	inner := parser.parseType()
+	if inner == nil {
+		return nil
+	}
+	return &ForallType{
+		Variables: vars,
+		Type:      inner,
+		Position:  token,
+	}
+}
+
 func (parser *Parser) cur() lexer.Token {
 	if parser.position >= len(parser.tokens) {
 		return lexer.Token{
@@ -594,6 +1439,13 @@ func (parser *Parser) cur() lexer.Token {
 	return parser.tokens[parser.position]
 }
 
+func (parser *Parser) next() lexer.Token {
+	if parser.position+1 >= len(parser.tokens) {
+		return lexer.Token{Type: lexer.EndOfFile}
+	}
+	return parser.tokens[parser.position+1]
+}
+
 func (parser *Parser) prev() lexer.Token {
 	if parser.position != 0 {
 		return parser.tokens[parser.position-1]
@@ -617,3 +1469,49 @@ func (parser *Parser) expect(typ lexer.TokenType) lexer.Token {
 	parser.advance()
 	return token
 }
+
+// unquote strips the quotes from a string or character literal and
+// decodes its escapes. \x takes two hex digits and \u four; ok is false
+// if they are missing.
+func unquote(lexeme string) (value string, ok bool) {
+	content := lexeme[1 : len(lexeme)-1]
+	var out strings.Builder
+	for i := 0; i < len(content); i++ {
+		if content[i] != '\\' {
+			out.WriteByte(content[i])
+			continue
+		}
+		i++
+		switch esc := content[i]; esc {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case '0':
+			out.WriteByte(0)
+		case 'x', 'u':
+			digits := 2
+			if esc == 'u' {
+				digits = 4
+			}
+			if i+digits >= len(content) {
+				return "", false
+			}
+			code, err := strconv.ParseUint(content[i+1:i+1+digits], 16, 32)
+			if err != nil {
+				return "", false
+			}
+			if esc == 'x' {
+				out.WriteByte(byte(code))
+			} else {
+				out.WriteRune(rune(code))
+			}
+			i += digits
+		default:
+			out.WriteByte(esc)
+		}
+	}
+	return out.String(), true
+}
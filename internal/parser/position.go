@@ -0,0 +1,110 @@
+package parser
+
+import "lunno/internal/lexer"
+
+// StartToken returns the first token of a node, which is where errors
+// about the node as a whole point. Postfix and infix expressions start at
+// their left operand rather than at their operator.
+func StartToken(node Node) lexer.Token {
+	switch n := node.(type) {
+	case *Identifier:
+		return n.Position
+	case *HoleExpression:
+		return n.Position
+	case *IntegerLiteral:
+		return n.Position
+	case *FloatLiteral:
+		return n.Position
+	case *StringLiteral:
+		return n.Position
+	case *CharacterLiteral:
+		return n.Position
+	case *BooleanLiteral:
+		return n.Position
+	case *UnitLiteral:
+		return n.Position
+	case *ListExpression:
+		return n.Position
+	case *TupleExpression:
+		return n.Position
+	case *IndexExpression:
+		return StartToken(n.Target)
+	case *SliceExpression:
+		return StartToken(n.Target)
+	case *PrefixExpression:
+		return n.Position
+	case *InfixExpression:
+		return StartToken(n.Left)
+	case *CallExpression:
+		return StartToken(n.Callee)
+	case *FieldAccessExpression:
+		return StartToken(n.Target)
+	case *PropagateExpression:
+		return StartToken(n.Target)
+	case *TryExpression:
+		return n.Position
+	case *LazyExpression:
+		return n.Position
+	case *VariableDeclarationExpression:
+		return n.Position
+	case *DestructuringDeclarationExpression:
+		return n.Position
+	case *FunctionLiteralExpression:
+		return n.Position
+	case *FunctionDeclarationExpression:
+		return n.Position
+	case *RecursiveGroupExpression:
+		return n.Position
+	case *ClassDeclarationExpression:
+		return n.Position
+	case *RecordDeclarationExpression:
+		return n.Position
+	case *TypeAliasDeclarationExpression:
+		return n.Position
+	case *NewtypeDeclarationExpression:
+		return n.Position
+	case *RecordExpression:
+		return n.Position
+	case *BlockExpression:
+		return n.Position
+	case *IfExpression:
+		return n.Position
+	case *MatchExpression:
+		return n.Position
+	case *ImportExpression:
+		return n.Position
+	case *WildcardPattern:
+		return n.Position
+	case *IdentifierPattern:
+		return n.Position
+	case *LiteralPattern:
+		return n.Position
+	case *NilPattern:
+		return n.Position
+	case *ListPattern:
+		return n.Position
+	case *TuplePattern:
+		return n.Position
+	case *RecordPattern:
+		return n.Position
+	case *ConstructorPattern:
+		return n.Position
+	case *AsPattern:
+		return n.Position
+	case *OrPattern:
+		return n.Position
+	case *SimpleType:
+		return n.Pos
+	case *GenericType:
+		return n.Position
+	case *ListType:
+		return n.Position
+	case *TupleType:
+		return n.Position
+	case *ForallType:
+		return n.Position
+	case *FunctionType:
+		return n.Position
+	}
+	return lexer.Token{}
+}
@@ -1,13 +1,100 @@
 package typechecker
 
-func registerBuiltins(env *Env) {
-	tv := &TypeVar{ID: 0}
-	s := &Scheme{
+// panicClass names the record a `recover` binding receives: the panic's
+// message and where it was raised.
+const panicClass = "Panic"
+
+// lazyClass is the type of a `lazy` expression. It has no constructors;
+// builtin_force is the only way to get at the value.
+const lazyClass = "Lazy"
+
+// refClass is the type of a mutable cell, made by builtin_ref, read by
+// builtin_get and written by builtin_set or `r := v`.
+const refClass = "Ref"
+
+func registerBuiltins(checker *Checker) {
+	env := checker.env
+	env.setClass(panicClass, &ClassDef{
+		Name:   panicClass,
+		Public: true,
+		Record: true,
+		Constructors: []*ConstructorDef{{
+			Name:       panicClass,
+			Class:      panicClass,
+			FieldNames: []string{"message", "file", "line", "column"},
+			Fields:     []Type{&StringType{}, &StringType{}, &IntType{}, &IntType{}},
+			Public:     true,
+		}},
+	})
+	tv := checker.freshVar()
+	env.setClass(lazyClass, &ClassDef{
+		Name:       lazyClass,
+		TypeParams: []int{tv.ID},
+		Public:     true,
+	})
+	env.set("builtin_force", &Scheme{
 		TypeVars: []int{tv.ID},
 		Type: &FunctionType{
-			Parameters: []Type{&StringType{}},
+			Parameters: []Type{lazyOf(tv)},
+			Return:     tv,
+		},
+	})
+	tv = checker.freshVar()
+	env.setClass(refClass, &ClassDef{
+		Name:       refClass,
+		TypeParams: []int{tv.ID},
+		Public:     true,
+	})
+	env.set("builtin_ref", &Scheme{
+		TypeVars: []int{tv.ID},
+		Type: &FunctionType{
+			Parameters: []Type{tv},
+			Return:     refOf(tv),
+		},
+	})
+	env.set("builtin_get", &Scheme{
+		TypeVars: []int{tv.ID},
+		Type: &FunctionType{
+			Parameters: []Type{refOf(tv)},
+			Return:     tv,
+		},
+	})
+	env.set("builtin_set", &Scheme{
+		TypeVars: []int{tv.ID},
+		Type: &FunctionType{
+			Parameters: []Type{refOf(tv), tv},
+			Return:     &UnitType{},
+		},
+	})
+	tv = checker.freshVar()
+	env.set("builtin_print", &Scheme{
+		TypeVars:    []int{tv.ID},
+		Constraints: []Constraint{{Class: "Show", Type: tv}},
+		Type: &FunctionType{
+			Parameters: []Type{tv},
 			Return:     &UnitType{},
 		},
+	})
+	tv = checker.freshVar()
+	env.set("builtin_panic", &Scheme{
+		TypeVars: []int{tv.ID},
+		Type: &FunctionType{
+			Parameters: []Type{&StringType{}},
+			Return:     tv,
+		},
+	})
+	for _, name := range []string{"builtin_floor", "builtin_ceil"} {
+		env.set(name, &Scheme{Type: &FunctionType{
+			Parameters: []Type{&FloatType{}},
+			Return:     &IntType{},
+		}})
 	}
-	env.set("builtin_print", s)
+}
+
+func lazyOf(t Type) Type {
+	return &NamedType{Name: lazyClass, Arguments: []Type{t}}
+}
+
+func refOf(t Type) Type {
+	return &NamedType{Name: refClass, Arguments: []Type{t}}
 }
@@ -0,0 +1,85 @@
+package typechecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type Cache struct {
+	Dir    string
+	Events []CacheEvent
+}
+
+type CacheEvent struct {
+	Module string
+	Hash   string
+	Hit    bool
+	Reason string
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+func (c *Cache) path(hash string) string {
+	return filepath.Join(c.Dir, hash[:2], hash+".lni")
+}
+
+func (c *Cache) lookup(hash string) (*Interface, string) {
+	data, err := os.ReadFile(c.path(hash))
+	if err != nil {
+		return nil, "no cached interface"
+	}
+	var iface Interface
+	if err := json.Unmarshal(data, &iface); err != nil {
+		return nil, "corrupt interface: " + err.Error()
+	}
+	if iface.Version != interfaceVersion {
+		return nil, fmt.Sprintf("interface version %d is stale", iface.Version)
+	}
+	if iface.SourceHash != hash {
+		return nil, "interface hash mismatch"
+	}
+	return &iface, ""
+}
+
+func (c *Cache) store(iface *Interface) error {
+	data, err := json.MarshalIndent(iface, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := c.path(iface.SourceHash)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (c *Cache) record(module, hash string, hit bool, reason string) {
+	c.Events = append(c.Events, CacheEvent{
+		Module: module,
+		Hash:   hash,
+		Hit:    hit,
+		Reason: reason,
+	})
+}
+
+func (c *Cache) Hits() int {
+	n := 0
+	for _, e := range c.Events {
+		if e.Hit {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *Cache) Misses() int {
+	return len(c.Events) - c.Hits()
+}
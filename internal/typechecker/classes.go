@@ -0,0 +1,169 @@
+package typechecker
+
+import (
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+)
+
+type ClassDef struct {
+	Name         string
+	Module       string
+	TypeParams   []int
+	Constructors []*ConstructorDef
+	Public       bool
+	Record       bool
+	// Newtype marks a type declared with newtype. Its one constructor,
+	// named after the type, wraps a single value and is erased at runtime.
+	Newtype bool
+	// Alias is the type a type alias stands for. An alias has no
+	// constructors and never appears as a NamedType.
+	Alias Type
+}
+
+type ConstructorDef struct {
+	Name       string
+	Class      string
+	Tag        int
+	FieldNames []string
+	Fields     []Type
+	Public     bool
+}
+
+func (class *ClassDef) self() *NamedType {
+	args := make([]Type, len(class.TypeParams))
+	for i, id := range class.TypeParams {
+		args[i] = &TypeVar{ID: id}
+	}
+	return &NamedType{
+		Name:      class.Name,
+		Arguments: args,
+	}
+}
+
+func (class *ClassDef) instantiate(checker *Checker) Type {
+	if class.Alias != nil {
+		return instantiate(&Scheme{TypeVars: class.TypeParams, Type: class.Alias}, checker)
+	}
+	return instantiate(&Scheme{TypeVars: class.TypeParams, Type: class.self()}, checker)
+}
+
+// expand returns the type an alias stands for with its parameters
+// replaced by args.
+func (class *ClassDef) expand(args []Type) Type {
+	s := Subst{}
+	for i, id := range class.TypeParams {
+		s[id] = args[i]
+	}
+	return apply(class.Alias, s)
+}
+
+func (class *ClassDef) constructor(name string) (*ConstructorDef, bool) {
+	for _, c := range class.Constructors {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+func (ctor *ConstructorDef) scheme(class *ClassDef) *Scheme {
+	var t Type = class.self()
+	if len(ctor.Fields) > 0 {
+		t = &FunctionType{
+			Parameters: ctor.Fields,
+			Return:     t,
+		}
+	}
+	return &Scheme{
+		TypeVars: class.TypeParams,
+		Type:     t,
+	}
+}
+
+func (checker *Checker) declareClass(e *parser.ClassDeclarationExpression) {
+	name := e.Name.Lexeme
+	if _, exists := checker.env.classes[name]; exists {
+		checker.errors = append(checker.errors, errorAt(CodeDuplicate, e.Name, "class %s is already declared", name))
+		return
+	}
+	class := &ClassDef{
+		Name:   name,
+		Module: checker.module,
+		Public: e.Public,
+	}
+	params := checker.bindTypeParameters(class, e.TypeParameters)
+	checker.env.setClass(name, class)
+
+	old, oldMode := checker.typeParams, checker.typeVarMode
+	checker.typeParams, checker.typeVarMode = params, classTypeVars
+	defer func() { checker.typeParams, checker.typeVarMode = old, oldMode }()
+	for i, c := range e.Constructors {
+		if _, dup := class.constructor(c.Name.Lexeme); dup {
+			checker.errors = append(checker.errors,
+				errorAt(CodeDuplicate, c.Name, "duplicate constructor %s in class %s", c.Name.Lexeme, name))
+			continue
+		}
+		for _, other := range checker.env.findConstructor(c.Name.Lexeme) {
+			if _, local := checker.env.classes[other.Name]; local && other != class {
+				checker.errors = append(checker.errors, errorAt(CodeDuplicate, c.Name,
+					"constructor %s is already declared by class %s", c.Name.Lexeme, other.Name))
+				break
+			}
+		}
+		ctor := &ConstructorDef{
+			Name:   c.Name.Lexeme,
+			Class:  name,
+			Tag:    i,
+			Public: c.Public,
+		}
+		for _, f := range c.Fields {
+			ft := checker.resolveType(f.Type)
+			for _, id := range freeTypeVars(ft) {
+				if !contains(class.TypeParams, id) {
+					checker.errors = append(checker.errors,
+						errorAt(CodeInvalid, f.Name, "constructor %s.%s uses a type variable not declared by the class", name, ctor.Name))
+					break
+				}
+			}
+			ctor.FieldNames = append(ctor.FieldNames, f.Name.Lexeme)
+			ctor.Fields = append(ctor.Fields, ft)
+		}
+		class.Constructors = append(class.Constructors, ctor)
+		checker.setConstructor(ctor.Name, ctor.scheme(class))
+	}
+}
+
+func (checker *Checker) setConstructor(name string, s *Scheme) {
+	checker.constructors[s] = true
+	checker.env.set(name, s)
+}
+
+func (checker *Checker) bindTypeParameters(class *ClassDef, tokens []lexer.Token) map[string]Type {
+	params := map[string]Type{}
+	for _, p := range tokens {
+		if _, dup := params[p.Lexeme]; dup {
+			checker.errors = append(checker.errors,
+				errorAt(CodeDuplicate, p, "duplicate type parameter %s in %s", p.Lexeme, class.Name))
+			continue
+		}
+		tv := checker.freshVar()
+		params[p.Lexeme] = tv
+		class.TypeParams = append(class.TypeParams, tv.ID)
+	}
+	return params
+}
+
+func (checker *Checker) checkFieldAccess(e *parser.FieldAccessExpression) Type {
+	if id, ok := e.Target.(*parser.Identifier); ok {
+		if _, shadowed := checker.env.get(id.Name); !shadowed {
+			if _, ok := checker.env.getClass(id.Name); ok {
+				class, ctor, ok := checker.lookupConstructor(id.Name, e.Field.Lexeme, e.Field)
+				if !ok {
+					return checker.freshVar()
+				}
+				return instantiate(ctor.scheme(class), checker)
+			}
+		}
+	}
+	return checker.recordField(checker.checkExpr(e.Target), e.Field)
+}
@@ -1,14 +1,25 @@
 package typechecker
 
+import (
+	"lunno/internal/lexer"
+	"sort"
+)
+
 type Env struct {
-	parent *Env
-	values map[string]*Scheme
+	parent  *Env
+	values  map[string]*Scheme
+	classes map[string]*ClassDef
+	// origins holds the token that declared each value, for errors that
+	// point back at a declaration.
+	origins map[string]lexer.Token
 }
 
 func newEnv(parent *Env) *Env {
 	return &Env{
-		parent: parent,
-		values: map[string]*Scheme{},
+		parent:  parent,
+		values:  map[string]*Scheme{},
+		classes: map[string]*ClassDef{},
+		origins: map[string]lexer.Token{},
 	}
 }
 
@@ -25,3 +36,59 @@ func (env *Env) get(name string) (*Scheme, bool) {
 func (env *Env) set(name string, s *Scheme) {
 	env.values[name] = s
 }
+
+func (env *Env) declare(name lexer.Token) {
+	env.origins[name.Lexeme] = name
+}
+
+func (env *Env) origin(name string) (lexer.Token, bool) {
+	if _, ok := env.values[name]; ok {
+		t, ok := env.origins[name]
+		return t, ok
+	}
+	if env.parent != nil {
+		return env.parent.origin(name)
+	}
+	return lexer.Token{}, false
+}
+
+func (env *Env) getClass(name string) (*ClassDef, bool) {
+	if c, ok := env.classes[name]; ok {
+		return c, true
+	}
+	if env.parent != nil {
+		return env.parent.getClass(name)
+	}
+	return nil, false
+}
+
+func (env *Env) setClass(name string, c *ClassDef) {
+	env.classes[name] = c
+}
+
+// findConstructor returns the classes declaring a constructor with the
+// given bare name in the nearest scope that has one, sorted by name. More
+// than one class means the bare name is ambiguous.
+func (env *Env) findConstructor(name string) []*ClassDef {
+	var out []*ClassDef
+	for _, c := range env.classes {
+		if _, ok := c.constructor(name); ok {
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 && env.parent != nil {
+		return env.parent.findConstructor(name)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// uniqueConstructor returns the one class declaring a bare constructor
+// name, if exactly one does.
+func (env *Env) uniqueConstructor(name string) (*ClassDef, bool) {
+	classes := env.findConstructor(name)
+	if len(classes) != 1 {
+		return nil, false
+	}
+	return classes[0], true
+}
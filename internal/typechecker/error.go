@@ -0,0 +1,184 @@
+package typechecker
+
+import (
+	"errors"
+	"fmt"
+	"lunno/internal/diagnostics"
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+	"strings"
+)
+
+// Error codes group type errors by kind, so tools can tell them apart
+// without matching on messages.
+const (
+	CodeMismatch        = "E0001" // two types do not unify
+	CodeArity           = "E0002" // wrong number of arguments, fields or type arguments
+	CodeInfiniteType    = "E0003" // a type would have to contain itself
+	CodeNoInstance      = "E0004" // a type lacks a type class instance
+	CodeUndefined       = "E0005" // unknown name, type, constructor or field
+	CodePrivate         = "E0006" // a name is private to another module
+	CodeDuplicate       = "E0007" // a name is declared or bound twice
+	CodeAmbiguous       = "E0008" // a record, constructor or imported name could mean more than one thing
+	CodeInvalid         = "E0009" // a declaration or pattern is malformed
+	CodeNonExhaustive   = "E0010" // a match does not cover every value
+	CodeUnreachable     = "E0011" // a match arm can never be taken
+	CodeModule          = "E0012" // an import cannot be loaded
+	CodeSignature       = "E0013" // a value is less general than its signature
+	CodeNotApplicable   = "E0014" // a value cannot be called, indexed or updated
+	CodeMissingField    = "E0015" // a record literal leaves fields unset
+	CodeGuardedCoverage = "E0016" // a match is only covered by guarded arms
+	CodeHole            = "E0017" // a typed hole is left in the program
+)
+
+// TypeError is an error found while typechecking, located at the
+// expression it is about.
+type TypeError struct {
+	Code    string
+	Span    diagnostics.Span
+	Message string
+	// Expected and Found are the conflicting types of a mismatch.
+	Expected Type
+	Found    Type
+	// Related points at the places the conflicting types came from.
+	Related []diagnostics.Label
+	// Notes add detail that has no place in the source.
+	Notes []string
+	// names numbers the type variables the error mentions.
+	names typeNames
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Span.File, e.Span.Line, e.Span.Column, e.Message)
+}
+
+// Render formats e with a snippet of its span and of each related span.
+// source returns the text of a file.
+func (e *TypeError) Render(source func(file string) []rune) string {
+	return e.render("error", source)
+}
+
+func (e *TypeError) render(severity string, source func(file string) []rune) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s[%s]: %s\n", severity, e.Code, e.Message)
+	var note string
+	if e.Expected != nil && e.Found != nil {
+		note = fmt.Sprintf("expected %s, found %s", e.Expected, e.Found)
+	}
+	out.WriteString(diagnostics.Snippet(source(e.Span.File), e.Span, note))
+	for _, r := range e.Related {
+		out.WriteString(diagnostics.Snippet(source(r.Span.File), r.Span, r.Message))
+	}
+	for _, note := range e.Notes {
+		fmt.Fprintf(&out, "   = note: %s\n", note)
+	}
+	return out.String()
+}
+
+func errorAt(code string, token lexer.Token, format string, args ...any) *TypeError {
+	names := typeNames{}
+	return &TypeError{
+		Code:    code,
+		Span:    token.Span(),
+		Message: names.sprintf(format, args...),
+		names:   names,
+	}
+}
+
+// errorAtNode locates an error at the start of node.
+func errorAtNode(code string, node parser.Node, format string, args ...any) *TypeError {
+	return errorAt(code, parser.StartToken(node), format, args...)
+}
+
+func (e *TypeError) types(expected, found Type) *TypeError {
+	e.Expected, e.Found = e.names.show(expected), e.names.show(found)
+	return e
+}
+
+func (e *TypeError) related(token lexer.Token, format string, args ...any) *TypeError {
+	e.Related = append(e.Related, diagnostics.Label{Span: token.Span(), Message: e.names.sprintf(format, args...)})
+	return e
+}
+
+// typeNames numbers the type variables of one error from T0 in the order
+// they are shown, so the checker's own numbers never reach the user and
+// every type in the error agrees on which variable is which.
+type typeNames map[int]int
+
+func (names typeNames) show(t Type) Type {
+	for _, id := range freeTypeVars(t) {
+		if _, ok := names[id]; !ok {
+			names[id] = len(names)
+		}
+	}
+	return rename(t, names)
+}
+
+// sprintf formats a message, showing the types among args, and in any
+// typeFailure among them, through names.
+func (names typeNames) sprintf(format string, args ...any) string {
+	shown := make([]any, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case Type:
+			shown[i] = names.show(arg)
+		case Constraint:
+			shown[i] = Constraint{Class: arg.Class, Type: names.show(arg.Type)}
+		case *typeFailure:
+			shown[i] = names.sprintf(arg.format, arg.args...)
+		case *infiniteTypeError:
+			shown[i] = names.sprintf("infinite type: %s occurs in %s", arg.v, arg.t)
+		default:
+			shown[i] = arg
+		}
+	}
+	return fmt.Sprintf(format, shown...)
+}
+
+// typeFailure is an error about types, such as a failed unification. It
+// keeps its types unprinted until it is reported, so they are numbered
+// along with the rest of the error that reports it.
+type typeFailure struct {
+	format string
+	args   []any
+}
+
+func failure(format string, args ...any) *typeFailure {
+	return &typeFailure{format: format, args: args}
+}
+
+func (f *typeFailure) Error() string {
+	return fmt.Sprintf(f.format, f.args...)
+}
+
+// unifyError reports a failed unification of expected and found at node.
+// An occurs check failure gets its own code.
+func (checker *Checker) unifyError(node parser.Node, err error, expected, found Type) *TypeError {
+	code := CodeMismatch
+	var infinite *infiniteTypeError
+	if errors.As(err, &infinite) {
+		code = CodeInfiniteType
+	}
+	return errorAtNode(code, node, "%v", err).types(checker.apply(expected), checker.apply(found))
+}
+
+type Warning struct {
+	Err error
+}
+
+func (w *Warning) Error() string {
+	return "warning: " + w.Err.Error()
+}
+
+func (w *Warning) Unwrap() error {
+	return w.Err
+}
+
+// Render formats the warning like TypeError.Render.
+func (w *Warning) Render(source func(file string) []rune) string {
+	var te *TypeError
+	if errors.As(w.Err, &te) {
+		return te.render("warning", source)
+	}
+	return w.Error() + "\n"
+}
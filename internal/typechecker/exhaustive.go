@@ -0,0 +1,432 @@
+package typechecker
+
+import (
+	"lunno/internal/parser"
+	"strings"
+)
+
+type patKind int
+
+const (
+	patWild patKind = iota
+	patCtor
+	patOr
+)
+
+type pat struct {
+	kind patKind
+	ctor *constructor
+	args []*pat
+	alts []*pat
+}
+
+// constructor is one case of a type's value space. sig lists every
+// constructor of the type; a nil sig marks an infinite domain such as int.
+type constructor struct {
+	name string
+	// value is the parsed value of a literal, which tells literals apart
+	// however they are written, so 1.0 and 1.00 are the same case. It is
+	// nil for other constructors, which are told apart by name.
+	value  any
+	arity  int
+	sig    []*constructor
+	list   bool
+	tuple  bool
+	fields []string
+}
+
+var (
+	boolSig = []*constructor{{name: "true"}, {name: "false"}}
+	listSig = []*constructor{{name: "[]", list: true}, {name: "::", arity: 2, list: true}}
+)
+
+func init() {
+	for _, c := range boolSig {
+		c.sig = boolSig
+	}
+	for _, c := range listSig {
+		c.sig = listSig
+	}
+}
+
+// literal is the identity of a literal constructor, kept apart from
+// constructor names so the string "nil" is not the nil pattern.
+type literal struct {
+	value any
+}
+
+func (c *constructor) id() any {
+	if c.value != nil {
+		return literal{c.value}
+	}
+	return c.name
+}
+
+func tupleConstructor(arity int) *constructor {
+	c := &constructor{name: "()", arity: arity, tuple: true}
+	c.sig = []*constructor{c}
+	return c
+}
+
+func wild() *pat {
+	return &pat{kind: patWild}
+}
+
+func wilds(n int) []*pat {
+	out := make([]*pat, n)
+	for i := range out {
+		out[i] = wild()
+	}
+	return out
+}
+
+func (p *pat) String() string {
+	switch p.kind {
+	case patOr:
+		parts := make([]string, len(p.alts))
+		for i, a := range p.alts {
+			parts[i] = a.String()
+		}
+		return strings.Join(parts, " | ")
+	case patCtor:
+		if p.ctor.list {
+			var elems []string
+			for cur := p; cur.kind == patCtor && cur.ctor.arity == 2; cur = cur.args[1] {
+				elems = append(elems, cur.args[0].String())
+			}
+			return "[" + strings.Join(elems, ", ") + "]"
+		}
+		if p.ctor.tuple {
+			parts := make([]string, len(p.args))
+			for i, a := range p.args {
+				parts[i] = a.String()
+			}
+			return "(" + strings.Join(parts, ", ") + ")"
+		}
+		if p.ctor.fields != nil {
+			parts := make([]string, len(p.args))
+			for i, a := range p.args {
+				parts[i] = p.ctor.fields[i] + ": " + a.String()
+			}
+			return "{ " + strings.Join(parts, ", ") + " }"
+		}
+		if len(p.args) == 0 {
+			return p.ctor.name
+		}
+		parts := make([]string, len(p.args))
+		for i, a := range p.args {
+			parts[i] = a.String()
+		}
+		return p.ctor.name + "(" + strings.Join(parts, ", ") + ")"
+	}
+	return "_"
+}
+
+func (checker *Checker) lowerPattern(p parser.Pattern, t Type) *pat {
+	switch p := p.(type) {
+	case *parser.AsPattern:
+		return checker.lowerPattern(p.Pattern, t)
+	case *parser.OrPattern:
+		alts := make([]*pat, len(p.Alternatives))
+		for i, a := range p.Alternatives {
+			alts[i] = checker.lowerPattern(a, t)
+		}
+		return &pat{kind: patOr, alts: alts}
+	case *parser.LiteralPattern:
+		return lowerLiteral(p.Value)
+	case *parser.NilPattern:
+		if _, ok := t.(*StringType); ok {
+			return &pat{kind: patCtor, ctor: &constructor{name: "nil", value: ""}}
+		}
+		return &pat{kind: patCtor, ctor: listSig[0]}
+	case *parser.ListPattern:
+		var elem Type
+		if lt, ok := t.(*ListType); ok {
+			elem = lt.Element
+		}
+		out := &pat{kind: patCtor, ctor: listSig[0]}
+		if p.Rest != nil {
+			out = wild()
+		}
+		for i := len(p.Elements) - 1; i >= 0; i-- {
+			out = &pat{
+				kind: patCtor,
+				ctor: listSig[1],
+				args: []*pat{checker.lowerPattern(p.Elements[i], elem), out},
+			}
+		}
+		return out
+	case *parser.TuplePattern:
+		var elems []Type
+		if tt, ok := t.(*TupleType); ok && len(tt.Elements) == len(p.Elements) {
+			elems = tt.Elements
+		}
+		args := make([]*pat, len(p.Elements))
+		for i, el := range p.Elements {
+			var et Type
+			if elems != nil {
+				et = elems[i]
+			}
+			args[i] = checker.lowerPattern(el, et)
+		}
+		return &pat{kind: patCtor, ctor: tupleConstructor(len(p.Elements)), args: args}
+	case *parser.RecordPattern:
+		return checker.lowerRecord(p, t)
+	case *parser.ConstructorPattern:
+		return checker.lowerConstructor(p, t)
+	}
+	return wild()
+}
+
+func lowerLiteral(e parser.Expression) *pat {
+	switch lit := e.(type) {
+	case *parser.BooleanLiteral:
+		if lit.Value {
+			return &pat{kind: patCtor, ctor: boolSig[0]}
+		}
+		return &pat{kind: patCtor, ctor: boolSig[1]}
+	case *parser.IntegerLiteral:
+		return &pat{kind: patCtor, ctor: &constructor{name: lit.Raw, value: lit.Value}}
+	case *parser.FloatLiteral:
+		return &pat{kind: patCtor, ctor: &constructor{name: lit.Raw, value: lit.Value}}
+	case *parser.StringLiteral:
+		return &pat{kind: patCtor, ctor: &constructor{name: lit.Raw, value: lit.Value}}
+	case *parser.CharacterLiteral:
+		return &pat{kind: patCtor, ctor: &constructor{name: lit.Raw, value: lit.Value}}
+	}
+	return wild()
+}
+
+func (checker *Checker) lowerConstructor(p *parser.ConstructorPattern, t Type) *pat {
+	var class *ClassDef
+	if p.Class != "" {
+		class, _ = checker.env.getClass(p.Class)
+	} else {
+		class, _ = checker.env.uniqueConstructor(p.Name)
+	}
+	if class == nil {
+		return wild()
+	}
+	sig := classSignature(class)
+	var ctor *constructor
+	var fields []Type
+	for i, c := range class.Constructors {
+		if c.Name == p.Name {
+			ctor = sig[i]
+			fields = c.Fields
+		}
+	}
+	if ctor == nil || len(p.Arguments) != ctor.arity {
+		return wild()
+	}
+	fieldSubst := Subst{}
+	if nt, ok := t.(*NamedType); ok && nt.Name == class.Name && len(nt.Arguments) == len(class.TypeParams) {
+		for i, id := range class.TypeParams {
+			fieldSubst[id] = nt.Arguments[i]
+		}
+	}
+	args := make([]*pat, len(p.Arguments))
+	for i, a := range p.Arguments {
+		args[i] = checker.lowerPattern(a, apply(fields[i], fieldSubst))
+	}
+	return &pat{kind: patCtor, ctor: ctor, args: args}
+}
+
+func (checker *Checker) lowerRecord(p *parser.RecordPattern, t Type) *pat {
+	class, ok := checker.records[p]
+	if !ok {
+		return wild()
+	}
+	ctor := class.Constructors[0]
+	fieldSubst := Subst{}
+	if nt, ok := t.(*NamedType); ok && nt.Name == class.Name && len(nt.Arguments) == len(class.TypeParams) {
+		for i, id := range class.TypeParams {
+			fieldSubst[id] = nt.Arguments[i]
+		}
+	}
+	args := wilds(len(ctor.Fields))
+	for _, f := range p.Fields {
+		if i, ok := ctor.field(f.Name.Lexeme); ok {
+			args[i] = checker.lowerPattern(f.Pattern, apply(ctor.Fields[i], fieldSubst))
+		}
+	}
+	return &pat{kind: patCtor, ctor: classSignature(class)[0], args: args}
+}
+
+func classSignature(class *ClassDef) []*constructor {
+	sig := make([]*constructor, len(class.Constructors))
+	for i, c := range class.Constructors {
+		sig[i] = &constructor{name: c.Name, arity: len(c.Fields)}
+		if class.Record {
+			sig[i].fields = c.FieldNames
+		}
+	}
+	for _, c := range sig {
+		c.sig = sig
+	}
+	return sig
+}
+
+func expandOr(rows [][]*pat) [][]*pat {
+	var out [][]*pat
+	for _, row := range rows {
+		if len(row) > 0 && row[0].kind == patOr {
+			for _, alt := range row[0].alts {
+				out = append(out, expandOr([][]*pat{prepend([]*pat{alt}, row[1:])})...)
+			}
+			continue
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func prepend(head []*pat, rest []*pat) []*pat {
+	out := make([]*pat, 0, len(head)+len(rest))
+	out = append(out, head...)
+	return append(out, rest...)
+}
+
+func specialize(rows [][]*pat, c *constructor) [][]*pat {
+	var out [][]*pat
+	for _, row := range rows {
+		head := row[0]
+		switch {
+		case head.kind == patWild:
+			out = append(out, prepend(wilds(c.arity), row[1:]))
+		case head.ctor.id() == c.id():
+			out = append(out, prepend(head.args, row[1:]))
+		}
+	}
+	return out
+}
+
+func defaultRows(rows [][]*pat) [][]*pat {
+	var out [][]*pat
+	for _, row := range rows {
+		if row[0].kind == patWild {
+			out = append(out, row[1:])
+		}
+	}
+	return out
+}
+
+func rebuild(c *constructor, witness []*pat) []*pat {
+	head := &pat{kind: patCtor, ctor: c, args: witness[:c.arity]}
+	return prepend([]*pat{head}, witness[c.arity:])
+}
+
+// useful reports whether some value matched by q is matched by none of rows,
+// returning such a value as a witness.
+func useful(rows [][]*pat, q []*pat) ([]*pat, bool) {
+	if len(q) == 0 {
+		return nil, len(rows) == 0
+	}
+	rows = expandOr(rows)
+	head := q[0]
+	switch head.kind {
+	case patOr:
+		for _, alt := range head.alts {
+			if w, ok := useful(rows, prepend([]*pat{alt}, q[1:])); ok {
+				return w, true
+			}
+		}
+		return nil, false
+	case patCtor:
+		w, ok := useful(specialize(rows, head.ctor), prepend(head.args, q[1:]))
+		if !ok {
+			return nil, false
+		}
+		return rebuild(head.ctor, w), true
+	}
+
+	seen := map[any]bool{}
+	var sig []*constructor
+	for _, row := range rows {
+		if row[0].kind == patCtor {
+			seen[row[0].ctor.id()] = true
+			sig = row[0].ctor.sig
+		}
+	}
+	var missing *constructor
+	for _, c := range sig {
+		if !seen[c.id()] {
+			missing = c
+			break
+		}
+	}
+	if sig != nil && missing == nil {
+		for _, c := range sig {
+			if w, ok := useful(specialize(rows, c), prepend(wilds(c.arity), q[1:])); ok {
+				return rebuild(c, w), true
+			}
+		}
+		return nil, false
+	}
+	w, ok := useful(defaultRows(rows), q[1:])
+	if !ok {
+		return nil, false
+	}
+	if missing == nil {
+		return prepend([]*pat{wild()}, w), true
+	}
+	return rebuild(missing, prepend(wilds(missing.arity), w)), true
+}
+
+// checkLetPattern requires the pattern of a destructuring let to match
+// every value of its type, unless the let has an else block to run when
+// it does not.
+func (checker *Checker) checkLetPattern(e *parser.DestructuringDeclarationExpression, target Type) {
+	rows := [][]*pat{{checker.lowerPattern(e.Pattern, target)}}
+	witness, refutable := useful(rows, []*pat{wild()})
+	switch {
+	case refutable && e.Else == nil:
+		checker.errors = append(checker.errors, errorAtNode(CodeNonExhaustive, e.Pattern,
+			"refutable pattern in let: `%s` not covered; add an else block", witness[0]))
+	case !refutable && e.Else != nil:
+		checker.errors = append(checker.errors, &Warning{
+			Err: errorAtNode(CodeUnreachable, e.Else, "else block of let is unreachable: the pattern always matches"),
+		})
+	}
+}
+
+// checkLetElse checks the else block of a destructuring let. The block
+// takes the place of the rest of the scope, so it must not finish with a
+// value: its type must be a variable nothing else constrains, as the type
+// of builtin_panic's result is.
+func (checker *Checker) checkLetElse(block parser.Expression) {
+	t := checker.checkExpr(block)
+	if tv, ok := t.(*TypeVar); ok && !contains(envFreeTypeVars(checker.env, checker.subst), tv.ID) {
+		return
+	}
+	checker.errors = append(checker.errors, errorAtNode(CodeMismatch, resultNode(block),
+		"else block of let must not finish with a value, found %s", t))
+}
+
+func (checker *Checker) checkExhaustive(e *parser.MatchExpression, target Type) {
+	var unguarded, all [][]*pat
+	for _, arm := range e.Arms {
+		row := []*pat{checker.lowerPattern(arm.Pattern, target)}
+		if _, ok := useful(unguarded, row); !ok {
+			checker.errors = append(checker.errors, &Warning{
+				Err: errorAt(CodeUnreachable, arm.Position, "unreachable match arm: earlier arms already cover %s", row[0]),
+			})
+		}
+		all = append(all, row)
+		if arm.Guard == nil {
+			unguarded = append(unguarded, row)
+		}
+	}
+	witness, ok := useful(unguarded, []*pat{wild()})
+	if !ok {
+		return
+	}
+	if _, stillMissing := useful(all, []*pat{wild()}); !stillMissing {
+		checker.errors = append(checker.errors, &Warning{
+			Err: errorAt(CodeGuardedCoverage, e.Position, "match may not be exhaustive: `%s` is only covered by guarded arms", witness[0]),
+		})
+		return
+	}
+	checker.errors = append(checker.errors,
+		errorAt(CodeNonExhaustive, e.Position, "non-exhaustive match: `%s` not covered", witness[0]))
+}
@@ -0,0 +1,131 @@
+package typechecker
+
+import (
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+)
+
+func (checker *Checker) checkCall(e *parser.CallExpression) Type {
+	callee := checker.checkExpr(e.Callee)
+	args := make([]Type, len(e.Arguments))
+	for i, arg := range e.Arguments {
+		args[i] = checker.checkExpr(arg)
+	}
+	switch ft := callee.(type) {
+	case *FunctionType:
+		if len(ft.Parameters) != len(args) {
+			te := errorAt(CodeArity, e.Position,
+				"function of type %s expects %d argument(s), got %d", ft, len(ft.Parameters), len(args))
+			checker.errors = append(checker.errors, checker.declaredHere(te, e.Callee))
+			return ft.Return
+		}
+		for i, arg := range args {
+			if err := checker.unify(ft.Parameters[i], arg); err != nil {
+				te := errorAtNode(CodeMismatch, e.Arguments[i],
+					"argument %d has type %s, expected %s", i+1, checker.apply(arg), checker.apply(ft.Parameters[i])).
+					types(checker.apply(ft.Parameters[i]), checker.apply(arg))
+				checker.errors = append(checker.errors, checker.declaredHere(te, e.Callee))
+			}
+		}
+		return ft.Return
+	case *TypeVar:
+		ret := checker.freshVar()
+		if err := checker.unify(ft, &FunctionType{Parameters: args, Return: ret}); err != nil {
+			checker.errors = append(checker.errors, checker.unifyError(e, err, ft, &FunctionType{Parameters: args, Return: ret}))
+		}
+		return ret
+	}
+	checker.errors = append(checker.errors, errorAtNode(CodeNotApplicable, e.Callee, "cannot call a value of type %s", callee))
+	return checker.freshVar()
+}
+
+// checkIndex types `xs[i]`, where xs is a list or a string. A target whose
+// type is not known yet is taken to be a list.
+func (checker *Checker) checkIndex(e *parser.IndexExpression) Type {
+	target := checker.checkExpr(e.Target)
+	checker.expectIndex(e.Index)
+	switch t := target.(type) {
+	case *StringType:
+		return &CharType{}
+	case *ListType:
+		return t.Element
+	case *TypeVar:
+		elem := checker.freshVar()
+		checker.unify(t, &ListType{Element: elem})
+		return elem
+	}
+	checker.errors = append(checker.errors, errorAtNode(CodeNotApplicable, e.Target, "cannot index a value of type %s", target))
+	return checker.freshVar()
+}
+
+func (checker *Checker) checkSlice(e *parser.SliceExpression) Type {
+	target := checker.checkExpr(e.Target)
+	if e.Start != nil {
+		checker.expectIndex(e.Start)
+	}
+	if e.End != nil {
+		checker.expectIndex(e.End)
+	}
+	switch t := target.(type) {
+	case *StringType, *ListType:
+		return t
+	case *TypeVar:
+		list := &ListType{Element: checker.freshVar()}
+		checker.unify(t, list)
+		return list
+	}
+	checker.errors = append(checker.errors, errorAtNode(CodeNotApplicable, e.Target, "cannot slice a value of type %s", target))
+	return checker.freshVar()
+}
+
+func (checker *Checker) expectIndex(index parser.Expression) {
+	t := checker.checkExpr(index)
+	if err := checker.unify(t, &IntType{}); err != nil {
+		checker.errors = append(checker.errors,
+			errorAtNode(CodeMismatch, index, "index must be int, found %s", t).types(&IntType{}, t))
+	}
+}
+
+// declaredHere points te back at the declaration of a called name.
+func (checker *Checker) declaredHere(te *TypeError, callee parser.Expression) *TypeError {
+	if id, ok := callee.(*parser.Identifier); ok {
+		if origin, ok := checker.env.origin(id.Name); ok {
+			te.related(origin, "%s is declared here", id.Name)
+		}
+	}
+	return te
+}
+
+// checkBlock checks a block in its own scope. Its type is the type of its
+// last expression, or unit when it is empty.
+func (checker *Checker) checkBlock(e *parser.BlockExpression) Type {
+	old := checker.env
+	checker.env = newEnv(old)
+	defer func() { checker.env = old }()
+	var t Type = &UnitType{}
+	for _, expr := range e.Expressions {
+		t = checker.checkExpr(expr)
+	}
+	return t
+}
+
+// checkTry checks `try { body } recover e -> handler`. The handler stands
+// in for the body's value when the body panics, so both have one type.
+func (checker *Checker) checkTry(e *parser.TryExpression) Type {
+	body := checker.checkExpr(e.Body)
+	old := checker.env
+	checker.env = newEnv(old)
+	if e.Binding.Type == lexer.Identifier {
+		t := &NamedType{Name: panicClass}
+		checker.recordName(e.Binding, t)
+		checker.env.declare(e.Binding)
+		checker.env.set(e.Binding.Lexeme, &Scheme{Type: t})
+	}
+	handler := checker.checkExpr(e.Handler)
+	checker.env = old
+	if err := checker.unify(body, handler); err != nil {
+		checker.errors = append(checker.errors, checker.unifyError(resultNode(e.Handler), err, body, handler).
+			related(parser.StartToken(resultNode(e.Body)), "the try body has type %s", checker.apply(body)))
+	}
+	return checker.apply(body)
+}
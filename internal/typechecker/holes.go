@@ -0,0 +1,105 @@
+package typechecker
+
+import (
+	"fmt"
+	"lunno/internal/parser"
+	"sort"
+	"strings"
+)
+
+// maxHoleFits caps how many fitting bindings a hole error lists.
+const maxHoleFits = 10
+
+type hole struct {
+	expr *parser.HoleExpression
+	t    Type
+	// scope holds the bindings visible at the hole, shadowing resolved.
+	scope map[string]*Scheme
+}
+
+// checkHole gives a hole a fresh type and remembers it, along with the
+// bindings in scope other than the declarations it is inside. Holes are
+// reported by reportHoles once the module is checked, when their types
+// are as solved as they will get.
+func (checker *Checker) checkHole(e *parser.HoleExpression) Type {
+	t := checker.freshVar()
+	scope := checker.env.visible()
+	for name, s := range scope {
+		for _, self := range checker.declaring {
+			if s == self {
+				delete(scope, name)
+			}
+		}
+	}
+	checker.holes = append(checker.holes, hole{expr: e, t: t, scope: scope})
+	return t
+}
+
+func (checker *Checker) reportHoles() {
+	for _, h := range checker.holes {
+		t := checker.apply(h.t)
+		te := errorAt(CodeHole, h.expr.Position, "hole `%s` has type `%s`", h.expr.Label(), t)
+		if fits := checker.holeFits(h, t); len(fits) > 0 {
+			te.Notes = append(te.Notes, "bindings that fit: "+strings.Join(fits, ", "))
+		}
+		checker.errors = append(checker.errors, te)
+	}
+	checker.holes = nil
+}
+
+// holeFits lists the bindings in scope at h whose type unifies with t,
+// including any type class constraints they carry. Every binding fits a
+// hole whose type is still a bare variable, so none are listed for one.
+func (checker *Checker) holeFits(h hole, t Type) []string {
+	if _, ok := t.(*TypeVar); ok {
+		return nil
+	}
+	var names []string
+	for name, s := range h.scope {
+		if checker.fits(s, t) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > maxHoleFits {
+		more := len(names) - maxHoleFits
+		names = append(names[:maxHoleFits], fmt.Sprintf("and %d more", more))
+	}
+	return names
+}
+
+// fits tries s against t in a copy of the substitution, so a failed or
+// successful attempt leaves the checker's own solution untouched.
+func (checker *Checker) fits(s *Scheme, t Type) bool {
+	trial := Subst{}
+	for id, bound := range checker.subst {
+		trial[id] = bound
+	}
+	fresh := Subst{}
+	for _, id := range s.TypeVars {
+		fresh[id] = checker.freshVar()
+	}
+	if unify(t, apply(s.Type, fresh), trial) != nil {
+		return false
+	}
+	for _, c := range s.Constraints {
+		if _, err := checker.solve(Constraint{Class: c.Class, Type: apply(apply(c.Type, fresh), trial)}); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// visible returns the bindings in scope, leaving out the builtins that
+// live in the outermost environment.
+func (env *Env) visible() map[string]*Scheme {
+	out := map[string]*Scheme{}
+	for e := env; e != nil && e.parent != nil; e = e.parent {
+		for name, s := range e.values {
+			if _, shadowed := out[name]; !shadowed {
+				out[name] = s
+			}
+		}
+	}
+	return out
+}
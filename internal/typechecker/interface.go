@@ -0,0 +1,262 @@
+package typechecker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+const interfaceVersion = 6
+
+type Interface struct {
+	Version    int                    `json:"version"`
+	Module     string                 `json:"module"`
+	SourceHash string                 `json:"source_hash"`
+	Imports    map[string]string      `json:"imports,omitempty"`
+	Exports    map[string]*schemeData `json:"exports"`
+	Classes    map[string]*classData  `json:"classes,omitempty"`
+	Private    []string               `json:"private,omitempty"`
+}
+
+type classData struct {
+	TypeParams   []int              `json:"type_params,omitempty"`
+	Constructors []*constructorData `json:"constructors"`
+	Record       bool               `json:"record,omitempty"`
+	Newtype      bool               `json:"newtype,omitempty"`
+	Alias        *typeData          `json:"alias,omitempty"`
+}
+
+type constructorData struct {
+	Name       string      `json:"name"`
+	FieldNames []string    `json:"field_names,omitempty"`
+	Fields     []*typeData `json:"fields,omitempty"`
+	Public     bool        `json:"public,omitempty"`
+}
+
+type schemeData struct {
+	TypeVars    []int             `json:"type_vars,omitempty"`
+	Constraints []*constraintData `json:"constraints,omitempty"`
+	Type        *typeData         `json:"type"`
+}
+
+type constraintData struct {
+	Class string    `json:"class"`
+	Type  *typeData `json:"type"`
+}
+
+type typeData struct {
+	Kind       string      `json:"kind"`
+	Name       string      `json:"name,omitempty"`
+	ID         int         `json:"id,omitempty"`
+	Element    *typeData   `json:"element,omitempty"`
+	Parameters []*typeData `json:"parameters,omitempty"`
+	Return     *typeData   `json:"return,omitempty"`
+}
+
+func sourceHash(name string, source []byte) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "lni%d\x00%s\x00", interfaceVersion, name)
+	h.Write(source)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newInterface(module *Module) *Interface {
+	iface := &Interface{
+		Version:    interfaceVersion,
+		Module:     module.Name,
+		SourceHash: module.Hash,
+		Imports:    module.Imports,
+		Exports:    map[string]*schemeData{},
+		Classes:    map[string]*classData{},
+	}
+	for name, class := range module.Classes {
+		data := &classData{TypeParams: class.TypeParams, Record: class.Record, Newtype: class.Newtype}
+		if class.Alias != nil {
+			data.Alias = encodeType(class.Alias)
+		}
+		for _, ctor := range class.Constructors {
+			data.Constructors = append(data.Constructors, &constructorData{
+				Name:       ctor.Name,
+				FieldNames: ctor.FieldNames,
+				Fields:     encodeTypes(ctor.Fields),
+				Public:     ctor.Public,
+			})
+		}
+		iface.Classes[name] = data
+	}
+	for name, s := range module.Exports {
+		data := &schemeData{
+			TypeVars: s.TypeVars,
+			Type:     encodeType(s.Type),
+		}
+		for _, c := range s.Constraints {
+			data.Constraints = append(data.Constraints, &constraintData{
+				Class: c.Class,
+				Type:  encodeType(c.Type),
+			})
+		}
+		iface.Exports[name] = data
+	}
+	for name := range module.private {
+		iface.Private = append(iface.Private, name)
+	}
+	sort.Strings(iface.Private)
+	return iface
+}
+
+func (iface *Interface) module(path string) (*Module, error) {
+	module := &Module{
+		Name:    iface.Module,
+		Path:    path,
+		Hash:    iface.SourceHash,
+		Imports: iface.Imports,
+		Exports: map[string]*Scheme{},
+		Classes: map[string]*ClassDef{},
+		private: map[string]bool{},
+	}
+	for name, data := range iface.Classes {
+		class := &ClassDef{
+			Name:       name,
+			Module:     iface.Module,
+			TypeParams: data.TypeParams,
+			Public:     true,
+			Record:     data.Record,
+			Newtype:    data.Newtype,
+		}
+		if data.Alias != nil {
+			alias, err := decodeType(data.Alias)
+			if err != nil {
+				return nil, fmt.Errorf("type alias %s: %v", name, err)
+			}
+			class.Alias = alias
+		}
+		for i, c := range data.Constructors {
+			fields, err := decodeTypes(c.Fields)
+			if err != nil {
+				return nil, fmt.Errorf("constructor %s.%s: %v", name, c.Name, err)
+			}
+			class.Constructors = append(class.Constructors, &ConstructorDef{
+				Name:       c.Name,
+				Class:      name,
+				Tag:        i,
+				FieldNames: c.FieldNames,
+				Fields:     fields,
+				Public:     c.Public,
+			})
+		}
+		module.Classes[name] = class
+	}
+	for name, s := range iface.Exports {
+		t, err := decodeType(s.Type)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %v", name, err)
+		}
+		scheme := &Scheme{
+			TypeVars: s.TypeVars,
+			Type:     t,
+		}
+		for _, c := range s.Constraints {
+			ct, err := decodeType(c.Type)
+			if err != nil {
+				return nil, fmt.Errorf("export %s: %v", name, err)
+			}
+			scheme.Constraints = append(scheme.Constraints, Constraint{Class: c.Class, Type: ct})
+		}
+		module.Exports[name] = scheme
+	}
+	for _, name := range iface.Private {
+		module.private[name] = true
+	}
+	return module, nil
+}
+
+func encodeType(t Type) *typeData {
+	switch t := t.(type) {
+	case *IntType, *FloatType, *BoolType,
+		*StringType, *CharType, *UnitType:
+		return &typeData{Kind: t.String()}
+	case *TypeVar:
+		return &typeData{Kind: "var", ID: t.ID}
+	case *ListType:
+		return &typeData{Kind: "list", Element: encodeType(t.Element)}
+	case *TupleType:
+		return &typeData{Kind: "tuple", Parameters: encodeTypes(t.Elements)}
+	case *FunctionType:
+		return &typeData{Kind: "fn", Parameters: encodeTypes(t.Parameters), Return: encodeType(t.Return)}
+	case *NamedType:
+		return &typeData{Kind: "named", Name: t.Name, Parameters: encodeTypes(t.Arguments)}
+	}
+	panic(fmt.Sprintf("encodeType: unsupported type %T", t))
+}
+
+func decodeType(d *typeData) (Type, error) {
+	if d == nil {
+		return nil, fmt.Errorf("missing type")
+	}
+	switch d.Kind {
+	case "int":
+		return &IntType{}, nil
+	case "float":
+		return &FloatType{}, nil
+	case "bool":
+		return &BoolType{}, nil
+	case "string":
+		return &StringType{}, nil
+	case "char":
+		return &CharType{}, nil
+	case "unit":
+		return &UnitType{}, nil
+	case "var":
+		return &TypeVar{ID: d.ID}, nil
+	case "list":
+		elem, err := decodeType(d.Element)
+		if err != nil {
+			return nil, err
+		}
+		return &ListType{Element: elem}, nil
+	case "tuple":
+		elems, err := decodeTypes(d.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		return &TupleType{Elements: elems}, nil
+	case "fn":
+		params, err := decodeTypes(d.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		ret, err := decodeType(d.Return)
+		if err != nil {
+			return nil, err
+		}
+		return &FunctionType{Parameters: params, Return: ret}, nil
+	case "named":
+		args, err := decodeTypes(d.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		return &NamedType{Name: d.Name, Arguments: args}, nil
+	}
+	return nil, fmt.Errorf("unknown type kind %q", d.Kind)
+}
+
+func encodeTypes(ts []Type) []*typeData {
+	out := make([]*typeData, len(ts))
+	for i, t := range ts {
+		out[i] = encodeType(t)
+	}
+	return out
+}
+
+func decodeTypes(ds []*typeData) ([]Type, error) {
+	out := make([]Type, len(ds))
+	for i, d := range ds {
+		t, err := decodeType(d)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = t
+	}
+	return out, nil
+}
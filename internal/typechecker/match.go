@@ -0,0 +1,92 @@
+package typechecker
+
+import (
+	"lunno/internal/decision"
+	"lunno/internal/parser"
+)
+
+type CompiledMatch struct {
+	Match *parser.MatchExpression
+	Tree  decision.Tree
+}
+
+func (checker *Checker) checkMatch(e *parser.MatchExpression) Type {
+	target := checker.checkExpr(e.Target)
+	errCount := len(checker.errors)
+	result := checker.freshVar()
+	for _, arm := range e.Arms {
+		bindings := map[string]Type{}
+		checker.checkPattern(arm.Pattern, target, bindings)
+		armEnv := newEnv(checker.env)
+		for name, t := range bindings {
+			armEnv.set(name, &Scheme{Type: checker.apply(t)})
+		}
+		old := checker.env
+		checker.env = armEnv
+		if arm.Guard != nil {
+			guard := checker.checkExpr(arm.Guard)
+			if err := checker.unify(guard, &BoolType{}); err != nil {
+				checker.errors = append(checker.errors, errorAtNode(CodeMismatch, arm.Guard,
+					"match guard must be bool, found %s", checker.apply(guard)).types(&BoolType{}, checker.apply(guard)))
+			}
+		}
+		body := checker.checkExpr(arm.Body)
+		checker.env = old
+		expected := checker.apply(result)
+		if err := checker.unify(result, body); err != nil {
+			checker.errors = append(checker.errors, errorAtNode(CodeMismatch, resultNode(arm.Body),
+				"match arm has type %s, but earlier arms have type %s", checker.apply(body), expected).
+				types(expected, checker.apply(body)).
+				related(parser.StartToken(resultNode(e.Arms[0].Body)), "the first arm has type %s", expected))
+		}
+	}
+	if len(checker.errors) == errCount {
+		checker.checkExhaustive(e, checker.apply(target))
+		if checker.result != nil {
+			checker.result.Matches = append(checker.result.Matches, CompiledMatch{
+				Match: e,
+				Tree:  decision.Compile(e, checker.lookupDecisionConstructor),
+			})
+		}
+	}
+	return checker.apply(result)
+}
+
+func (checker *Checker) lookupDecisionConstructor(pattern parser.Pattern) (decision.Constructor, bool) {
+	if p, ok := pattern.(*parser.RecordPattern); ok {
+		class, ok := checker.records[p]
+		if !ok {
+			return decision.Constructor{}, false
+		}
+		return decision.Constructor{
+			Name:   class.Name,
+			Arity:  len(class.Constructors[0].Fields),
+			Span:   1,
+			Fields: class.Constructors[0].FieldNames,
+		}, true
+	}
+	p, ok := pattern.(*parser.ConstructorPattern)
+	if !ok {
+		return decision.Constructor{}, false
+	}
+	var class *ClassDef
+	if p.Class != "" {
+		class, _ = checker.env.getClass(p.Class)
+	} else {
+		class, _ = checker.env.uniqueConstructor(p.Name)
+	}
+	if class == nil {
+		return decision.Constructor{}, false
+	}
+	ctor, ok := class.constructor(p.Name)
+	if !ok {
+		return decision.Constructor{}, false
+	}
+	return decision.Constructor{
+		Name:    ctor.Name,
+		Tag:     ctor.Tag,
+		Arity:   len(ctor.Fields),
+		Span:    len(class.Constructors),
+		Newtype: class.Newtype,
+	}, true
+}
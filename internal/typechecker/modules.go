@@ -0,0 +1,351 @@
+package typechecker
+
+import (
+	"errors"
+	"fmt"
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type Config struct {
+	SearchPaths []string
+	Cache       *Cache
+}
+
+// SearchPaths returns the directories that imports in the file at
+// filename are looked up in: the file's own directory, then each
+// directory in LUNNO_PATH, then the standard library.
+func SearchPaths(filename string) []string {
+	paths := []string{filepath.Dir(filename)}
+	paths = append(paths, filepath.SplitList(os.Getenv("LUNNO_PATH"))...)
+	return append(paths, stdlibDir())
+}
+
+// stdlibDir finds the standard library. LUNNO_ROOT names the directory
+// holding pkg/stdlib. Otherwise it is looked for next to the executable
+// and in its parent directory, where a release puts it, and last in the
+// working directory, which is where `go run` from the repository finds it.
+func stdlibDir() string {
+	rel := filepath.Join("pkg", "stdlib")
+	if root := os.Getenv("LUNNO_ROOT"); root != "" {
+		return filepath.Join(root, rel)
+	}
+	if exe, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		dir := filepath.Dir(exe)
+		for _, candidate := range []string{filepath.Join(dir, rel), filepath.Join(dir, "..", rel)} {
+			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+				return candidate
+			}
+		}
+	}
+	if abs, err := filepath.Abs(rel); err == nil {
+		return abs
+	}
+	return rel
+}
+
+type Module struct {
+	Name    string
+	Path    string
+	Hash    string
+	Imports map[string]string
+	Exports map[string]*Scheme
+	Classes map[string]*ClassDef
+	private map[string]bool
+}
+
+type loader struct {
+	paths   []string
+	cache   *Cache
+	modules map[string]*Module
+	loading map[string]bool
+}
+
+func newLoader(config Config) *loader {
+	return &loader{
+		paths:   config.SearchPaths,
+		cache:   config.Cache,
+		modules: map[string]*Module{},
+		loading: map[string]bool{},
+	}
+}
+
+func (l *loader) find(name string) (string, error) {
+	for _, dir := range l.paths {
+		path := filepath.Join(dir, name+".ln")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("module %s not found (searched: %s)", name, strings.Join(l.paths, ", "))
+}
+
+func (l *loader) load(name string) (*Module, []error) {
+	if m, ok := l.modules[name]; ok {
+		return m, nil
+	}
+	if l.loading[name] {
+		return nil, []error{fmt.Errorf("import cycle detected while loading module %s", name)}
+	}
+	path, err := l.find(name)
+	if err != nil {
+		return nil, []error{err}
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("module %s: %v", name, err)}
+	}
+	hash := sourceHash(name, source)
+
+	l.loading[name] = true
+	defer delete(l.loading, name)
+	if l.cache != nil {
+		if module := l.loadCached(name, path, hash); module != nil {
+			l.modules[name] = module
+			return module, nil
+		}
+	}
+
+	lx, tokens, err := lexer.Tokenize(string(source), path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("module %s: %v", name, err)}
+	}
+	program, parseErrs := parser.ParseProgram(tokens, lx)
+	if len(parseErrs) > 0 {
+		return nil, []error{fmt.Errorf("module %s: %d parse error(s), first: %s", name, len(parseErrs), parseErrs[0])}
+	}
+
+	checker := newChecker(l)
+	checker.module = name
+	checker.checkProgram(program.Expressions)
+	module := &Module{
+		Name:    name,
+		Path:    path,
+		Hash:    hash,
+		Imports: checker.imports,
+		Exports: map[string]*Scheme{},
+		Classes: map[string]*ClassDef{},
+		private: map[string]bool{},
+	}
+	for _, e := range declarations(program.Expressions) {
+		switch decl := e.(type) {
+		case *parser.ClassDeclarationExpression:
+			module.exportClass(checker, decl.Name.Lexeme)
+			continue
+		case *parser.RecordDeclarationExpression:
+			module.exportClass(checker, decl.Name.Lexeme)
+			continue
+		case *parser.TypeAliasDeclarationExpression:
+			module.exportClass(checker, decl.Name.Lexeme)
+			continue
+		case *parser.NewtypeDeclarationExpression:
+			module.exportClass(checker, decl.Name.Lexeme)
+			continue
+		}
+		name, public := declaredName(e)
+		if name == "" {
+			continue
+		}
+		s, ok := checker.env.values[name]
+		if !ok {
+			continue
+		}
+		if public {
+			module.Exports[name] = s
+		} else {
+			module.private[name] = true
+		}
+	}
+	l.modules[name] = module
+
+	// Type errors already carry the module's file in their spans.
+	errs := checker.errors
+	if l.cache != nil && len(errs) == 0 {
+		if err := l.cache.store(newInterface(module)); err != nil {
+			errs = append(errs, fmt.Errorf("module %s: writing interface: %v", name, err))
+		}
+	}
+	return module, errs
+}
+
+func (l *loader) loadCached(name, path, hash string) *Module {
+	iface, reason := l.cache.lookup(hash)
+	if iface == nil {
+		l.cache.record(name, hash, false, reason)
+		return nil
+	}
+	for dep, depHash := range iface.Imports {
+		module, errs := l.load(dep)
+		if module == nil || len(errs) > 0 || module.Hash != depHash {
+			l.cache.record(name, hash, false, "dependency "+dep+" changed")
+			return nil
+		}
+	}
+	module, err := iface.module(path)
+	if err != nil {
+		l.cache.record(name, hash, false, "corrupt interface: "+err.Error())
+		return nil
+	}
+	l.cache.record(name, hash, true, "")
+	return module
+}
+
+func (module *Module) exportClass(checker *Checker, name string) {
+	class, ok := checker.env.classes[name]
+	if !ok {
+		return
+	}
+	if class.Public {
+		module.Classes[class.Name] = class
+	}
+	for _, ctor := range class.Constructors {
+		s, ok := checker.env.values[ctor.Name]
+		if !ok {
+			continue
+		}
+		if class.Public && ctor.Public {
+			module.Exports[ctor.Name] = s
+		} else {
+			module.private[ctor.Name] = true
+		}
+	}
+}
+
+func declaredName(expr parser.Expression) (string, bool) {
+	switch e := expr.(type) {
+	case *parser.VariableDeclarationExpression:
+		return e.Name.Lexeme, e.Public
+	case *parser.FunctionDeclarationExpression:
+		return e.Name.Lexeme, e.Public
+	}
+	return "", false
+}
+
+func (checker *Checker) importModule(e *parser.ImportExpression) {
+	module, errs := checker.loader.load(e.Module)
+	for _, err := range errs {
+		var te *TypeError
+		if !errors.As(err, &te) {
+			err = errorAt(CodeModule, e.Position, "%v", err)
+		}
+		checker.errors = append(checker.errors, err)
+	}
+	if module == nil {
+		return
+	}
+	checker.imports[module.Name] = module.Hash
+	// Each module numbers its type variables from zero, so imported types
+	// are renamed into this checker's variables before use.
+	ids := map[int]int{}
+	for name, class := range module.Classes {
+		checker.env.setClass(name, checker.importClass(class, ids))
+	}
+	ctors := map[string]bool{}
+	for _, class := range module.Classes {
+		for _, ctor := range class.Constructors {
+			ctors[ctor.Name] = true
+		}
+	}
+	for name, s := range module.Exports {
+		if ctors[name] {
+			checker.setConstructor(name, checker.importScheme(s, ids))
+		} else {
+			imported := checker.importScheme(s, ids)
+			var from []string
+			if prev, ok := checker.env.values[name]; ok {
+				for _, m := range checker.importedFrom[prev] {
+					if m != module.Name {
+						from = append(from, m)
+					}
+				}
+			}
+			from = append(from, module.Name)
+			sort.Strings(from)
+			checker.importedFrom[imported] = from
+			checker.env.set(name, imported)
+		}
+		delete(checker.private, name)
+	}
+	for name := range module.private {
+		if _, exported := checker.env.get(name); !exported {
+			checker.private[name] = module.Name
+		}
+	}
+}
+
+func (checker *Checker) importClass(class *ClassDef, ids map[int]int) *ClassDef {
+	out := *class
+	out.TypeParams = checker.renameVars(class.TypeParams, ids)
+	if class.Alias != nil {
+		out.Alias = rename(class.Alias, ids)
+	}
+	out.Constructors = make([]*ConstructorDef, len(class.Constructors))
+	for i, ctor := range class.Constructors {
+		c := *ctor
+		c.Fields = make([]Type, len(ctor.Fields))
+		for j, f := range ctor.Fields {
+			c.Fields[j] = rename(f, ids)
+		}
+		out.Constructors[i] = &c
+	}
+	return &out
+}
+
+func (checker *Checker) importScheme(s *Scheme, ids map[int]int) *Scheme {
+	out := &Scheme{TypeVars: checker.renameVars(s.TypeVars, ids)}
+	out.Type = rename(s.Type, ids)
+	for _, c := range s.Constraints {
+		out.Constraints = append(out.Constraints, Constraint{Class: c.Class, Type: rename(c.Type, ids)})
+	}
+	return out
+}
+
+func (checker *Checker) renameVars(vars []int, ids map[int]int) []int {
+	out := make([]int, len(vars))
+	for i, id := range vars {
+		if _, ok := ids[id]; !ok {
+			ids[id] = checker.freshVar().ID
+		}
+		out[i] = ids[id]
+	}
+	return out
+}
+
+// rename replaces type variables in a single pass. Unlike apply it does not
+// chase the result, since renamed and original ids may overlap.
+func rename(t Type, ids map[int]int) Type {
+	switch t := t.(type) {
+	case *TypeVar:
+		if id, ok := ids[t.ID]; ok {
+			return &TypeVar{ID: id}
+		}
+	case *ListType:
+		return &ListType{Element: rename(t.Element, ids)}
+	case *TupleType:
+		elems := make([]Type, len(t.Elements))
+		for i, e := range t.Elements {
+			elems[i] = rename(e, ids)
+		}
+		return &TupleType{Elements: elems}
+	case *FunctionType:
+		params := make([]Type, len(t.Parameters))
+		for i, p := range t.Parameters {
+			params[i] = rename(p, ids)
+		}
+		return &FunctionType{Parameters: params, Return: rename(t.Return, ids)}
+	case *NamedType:
+		args := make([]Type, len(t.Arguments))
+		for i, a := range t.Arguments {
+			args[i] = rename(a, ids)
+		}
+		return &NamedType{Name: t.Name, Arguments: args}
+	}
+	return t
+}
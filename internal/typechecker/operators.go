@@ -0,0 +1,167 @@
+package typechecker
+
+import (
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+)
+
+func (checker *Checker) checkInfix(e *parser.InfixExpression) Type {
+	switch e.Operator.Type {
+	case lexer.PipeForward:
+		return checker.checkPipe(e)
+	case lexer.Compose:
+		return checker.checkCompose(e)
+	case lexer.And, lexer.Or:
+		checker.expectOperand(e.Operator, e.Left, &BoolType{})
+		checker.expectOperand(e.Operator, e.Right, &BoolType{})
+		return &BoolType{}
+	case lexer.Plus, lexer.Minus, lexer.Asterisk, lexer.Slash:
+		return checker.checkArithmetic(e)
+	case lexer.Equal, lexer.NotEqual:
+		checker.checkComparison(e, "Eq")
+		return &BoolType{}
+	case lexer.LessThan, lexer.GreaterThan, lexer.LessThanOrEqual, lexer.GreaterThanOrEqual:
+		checker.checkComparison(e, "Ord")
+		return &BoolType{}
+	case lexer.Percent:
+		checker.expectOperand(e.Operator, e.Left, &IntType{})
+		checker.expectOperand(e.Operator, e.Right, &IntType{})
+		return &IntType{}
+	case lexer.ColonAssign:
+		value := checker.checkExpr(e.Right)
+		checker.expectOperand(e.Operator, e.Left, refOf(value))
+		return &UnitType{}
+	}
+	checker.checkExpr(e.Left)
+	checker.checkExpr(e.Right)
+	checker.errors = append(checker.errors,
+		errorAt(CodeUndefined, e.Operator, "%s is not a binary operator", e.Operator.Lexeme))
+	return checker.freshVar()
+}
+
+func (checker *Checker) checkPrefix(e *parser.PrefixExpression) Type {
+	switch e.Operator.Type {
+	case lexer.Bang, lexer.KwNot:
+		checker.expectOperand(e.Operator, e.Right, &BoolType{})
+		return &BoolType{}
+	case lexer.Minus:
+		operand := checker.checkExpr(e.Right)
+		checker.require("Num", operand, e.Operator)
+		return operand
+	}
+	checker.checkExpr(e.Right)
+	checker.errors = append(checker.errors,
+		errorAt(CodeUndefined, e.Operator, "%s is not a prefix operator", e.Operator.Lexeme))
+	return checker.freshVar()
+}
+
+// operands checks both sides of a binary operator and unifies them, since
+// every overloaded operator takes two values of the same type.
+func (checker *Checker) operands(e *parser.InfixExpression) (Type, bool) {
+	left := checker.checkExpr(e.Left)
+	right := checker.checkExpr(e.Right)
+	if err := checker.unify(left, right); err != nil {
+		checker.errors = append(checker.errors, errorAt(CodeMismatch, e.Operator,
+			"operator %s needs operands of the same type, found %s and %s", e.Operator.Lexeme, left, right).
+			types(checker.apply(left), checker.apply(right)).
+			related(parser.StartToken(e.Left), "the left operand has type %s", checker.apply(left)))
+		return nil, false
+	}
+	checker.solvePending()
+	return checker.apply(left), true
+}
+
+// checkArithmetic requires Num for arithmetic operators. `+` also
+// concatenates strings and lists when the operands are known to be one.
+func (checker *Checker) checkArithmetic(e *parser.InfixExpression) Type {
+	t, ok := checker.operands(e)
+	if !ok {
+		return checker.freshVar()
+	}
+	if e.Operator.Type == lexer.Plus {
+		switch t.(type) {
+		case *StringType, *ListType:
+			return t
+		}
+	}
+	checker.require("Num", t, e.Operator)
+	return t
+}
+
+func (checker *Checker) checkComparison(e *parser.InfixExpression, class string) {
+	if t, ok := checker.operands(e); ok {
+		checker.require(class, t, e.Operator)
+	}
+}
+
+func (checker *Checker) expectOperand(op lexer.Token, operand parser.Expression, expected Type) {
+	t := checker.checkExpr(operand)
+	if err := checker.unify(t, expected); err != nil {
+		checker.errors = append(checker.errors, errorAt(CodeMismatch, op,
+			"operator %s expects %s, found %s", op.Lexeme, expected, t).types(expected, t))
+	}
+}
+
+// checkPipe types `x |> f(a, b)` as `f(a, b, x)` and `x |> f` as `f(x)`.
+func (checker *Checker) checkPipe(e *parser.InfixExpression) Type {
+	value := checker.checkExpr(e.Left)
+	var callee Type
+	var args []Type
+	if call, ok := e.Right.(*parser.CallExpression); ok {
+		callee = checker.checkExpr(call.Callee)
+		for _, arg := range call.Arguments {
+			args = append(args, checker.checkExpr(arg))
+		}
+	} else {
+		callee = checker.checkExpr(e.Right)
+	}
+	args = append(args, value)
+
+	ret := checker.freshVar()
+	if call, ok := e.Right.(*parser.CallExpression); ok {
+		// The call is never checked on its own, so record what it
+		// returns once the piped value is passed to it.
+		checker.recordType(call, ret)
+	}
+	switch ft := callee.(type) {
+	case *FunctionType:
+		if len(ft.Parameters) != len(args) {
+			checker.errors = append(checker.errors, errorAt(CodeArity, e.Position,
+				"|> calls a function of type %s with %d argument(s)", ft, len(args)))
+			return ret
+		}
+	case *TypeVar:
+	default:
+		checker.errors = append(checker.errors, errorAt(CodeNotApplicable, e.Position,
+			"right-hand side of |> must be a function, found %s", callee))
+		return ret
+	}
+	if err := checker.unify(callee, &FunctionType{Parameters: args, Return: ret}); err != nil {
+		checker.errors = append(checker.errors, errorAt(CodeMismatch, e.Position,
+			"|> cannot pass %s to a function of type %s", checker.apply(value), checker.apply(callee)))
+	}
+	return checker.apply(ret)
+}
+
+// checkCompose types `f >> g` as a function that applies f, then g.
+func (checker *Checker) checkCompose(e *parser.InfixExpression) Type {
+	left := checker.checkExpr(e.Left)
+	right := checker.checkExpr(e.Right)
+	params := []Type{checker.freshVar()}
+	if ft, ok := left.(*FunctionType); ok {
+		params = ft.Parameters
+	}
+	mid := checker.freshVar()
+	out := checker.freshVar()
+	if err := checker.unify(left, &FunctionType{Parameters: params, Return: mid}); err != nil {
+		checker.errors = append(checker.errors, errorAt(CodeNotApplicable, e.Position,
+			"left-hand side of >> must be a function, found %s", checker.apply(left)))
+		return out
+	}
+	if err := checker.unify(right, &FunctionType{Parameters: []Type{mid}, Return: out}); err != nil {
+		checker.errors = append(checker.errors, errorAt(CodeMismatch, e.Position,
+			">> cannot compose %s with %s", checker.apply(left), checker.apply(right)))
+		return out
+	}
+	return checker.apply(&FunctionType{Parameters: params, Return: out})
+}
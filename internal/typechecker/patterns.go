@@ -0,0 +1,172 @@
+package typechecker
+
+import (
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+	"strings"
+)
+
+func (checker *Checker) checkPattern(p parser.Pattern, expected Type, bindings map[string]Type) {
+	switch p := p.(type) {
+	case *parser.WildcardPattern:
+	case *parser.IdentifierPattern:
+		checker.bindPattern(p.Name, p.Position, expected, bindings)
+		checker.recordName(p.Position, expected)
+	case *parser.NilPattern:
+		if _, ok := checker.apply(expected).(*StringType); ok {
+			return
+		}
+		list := &ListType{Element: checker.freshVar()}
+		if err := checker.unify(expected, list); err != nil {
+			checker.errors = append(checker.errors,
+				errorAt(CodeMismatch, p.Position, "nil pattern matches lists and strings: %v", err).
+					types(checker.apply(expected), list))
+		}
+	case *parser.LiteralPattern:
+		value := checker.checkExpr(p.Value)
+		if err := checker.unify(expected, value); err != nil {
+			checker.errors = append(checker.errors, checker.unifyError(p, err, expected, value))
+		}
+	case *parser.ListPattern:
+		elem := checker.freshVar()
+		if err := checker.unify(expected, &ListType{Element: elem}); err != nil {
+			checker.errors = append(checker.errors, checker.unifyError(p, err, expected, &ListType{Element: elem}))
+		}
+		for _, el := range p.Elements {
+			checker.checkPattern(el, elem, bindings)
+		}
+		if p.Rest != nil {
+			checker.checkPattern(p.Rest, &ListType{Element: elem}, bindings)
+		}
+	case *parser.TuplePattern:
+		elems := make([]Type, len(p.Elements))
+		for i := range elems {
+			elems[i] = checker.freshVar()
+		}
+		if err := checker.unify(expected, &TupleType{Elements: elems}); err != nil {
+			checker.errors = append(checker.errors, checker.unifyError(p, err, expected, &TupleType{Elements: elems}))
+		}
+		for i, el := range p.Elements {
+			checker.checkPattern(el, elems[i], bindings)
+		}
+	case *parser.RecordPattern:
+		checker.checkRecordPattern(p, expected, bindings)
+	case *parser.ConstructorPattern:
+		checker.checkConstructorPattern(p, expected, bindings)
+	case *parser.AsPattern:
+		checker.bindPattern(p.Name, p.Position, expected, bindings)
+		checker.recordName(p.Position, expected)
+		checker.checkPattern(p.Pattern, expected, bindings)
+	case *parser.OrPattern:
+		var first map[string]Type
+		for i, alt := range p.Alternatives {
+			altBindings := map[string]Type{}
+			checker.checkPattern(alt, expected, altBindings)
+			if i == 0 {
+				first = altBindings
+				continue
+			}
+			if len(altBindings) != len(first) {
+				checker.errors = append(checker.errors,
+					errorAtNode(CodeInvalid, alt, "all alternatives of an or-pattern must bind the same variables"))
+				continue
+			}
+			for name, t := range altBindings {
+				ft, ok := first[name]
+				if !ok {
+					checker.errors = append(checker.errors,
+						errorAtNode(CodeInvalid, alt, "variable %s is not bound in every alternative of the or-pattern", name))
+					continue
+				}
+				if err := checker.unify(ft, t); err != nil {
+					checker.errors = append(checker.errors, checker.unifyError(alt, err, ft, t))
+				}
+			}
+		}
+		for name, t := range first {
+			checker.bindPattern(name, p.Position, t, bindings)
+		}
+	default:
+		checker.errors = append(checker.errors, errorAtNode(CodeInvalid, p, "unsupported pattern %s", p.NodeType()))
+	}
+}
+
+func (checker *Checker) bindPattern(name string, at lexer.Token, t Type, bindings map[string]Type) {
+	if _, dup := bindings[name]; dup {
+		checker.errors = append(checker.errors,
+			errorAt(CodeDuplicate, at, "variable %s is bound more than once in the same pattern", name))
+		return
+	}
+	bindings[name] = t
+}
+
+func (checker *Checker) checkConstructorPattern(p *parser.ConstructorPattern, expected Type, bindings map[string]Type) {
+	class, ctor, ok := checker.lookupConstructor(p.Class, p.Name, p.Position)
+	if !ok {
+		// Still bind the arguments' variables, so the arm's body does not
+		// report each of them as undefined.
+		for _, arg := range p.Arguments {
+			checker.checkPattern(arg, checker.freshVar(), bindings)
+		}
+		return
+	}
+	fresh := Subst{}
+	for _, id := range class.TypeParams {
+		fresh[id] = checker.freshVar()
+	}
+	self := apply(class.self(), fresh)
+	if err := checker.unify(expected, self); err != nil {
+		checker.errors = append(checker.errors, checker.unifyError(p, err, expected, self))
+	}
+	if len(p.Arguments) != len(ctor.Fields) {
+		checker.errors = append(checker.errors,
+			errorAt(CodeArity, p.Position, "constructor %s expects %d argument(s), got %d",
+				ctor.Name, len(ctor.Fields), len(p.Arguments)))
+		return
+	}
+	for i, arg := range p.Arguments {
+		checker.checkPattern(arg, apply(ctor.Fields[i], fresh), bindings)
+	}
+}
+
+func (checker *Checker) lookupConstructor(className, name string, at lexer.Token) (*ClassDef, *ConstructorDef, bool) {
+	var class *ClassDef
+	var ctor *ConstructorDef
+	if className != "" {
+		c, ok := checker.env.getClass(className)
+		if !ok {
+			checker.errors = append(checker.errors, errorAt(CodeUndefined, at, "unknown type %s", className))
+			return nil, nil, false
+		}
+		class = c
+		ctor, ok = class.constructor(name)
+		if !ok {
+			checker.errors = append(checker.errors,
+				errorAt(CodeUndefined, at, "class %s has no constructor %s", className, name))
+			return nil, nil, false
+		}
+	} else {
+		classes := checker.env.findConstructor(name)
+		switch len(classes) {
+		case 0:
+			checker.errors = append(checker.errors, errorAt(CodeUndefined, at, "unknown constructor %s", name))
+			return nil, nil, false
+		case 1:
+			class = classes[0]
+			ctor, _ = class.constructor(name)
+		default:
+			names := make([]string, len(classes))
+			for i, c := range classes {
+				names[i] = c.Name + "." + name
+			}
+			checker.errors = append(checker.errors, errorAt(CodeAmbiguous, at,
+				"ambiguous constructor %s: write one of %s", name, strings.Join(names, ", ")))
+			return nil, nil, false
+		}
+	}
+	if !ctor.Public && class.Module != checker.module {
+		checker.errors = append(checker.errors,
+			errorAt(CodePrivate, at, "constructor %s.%s is private to module %s", class.Name, ctor.Name, class.Module))
+	}
+	return class, ctor, true
+}
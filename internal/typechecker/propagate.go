@@ -0,0 +1,64 @@
+package typechecker
+
+import "lunno/internal/parser"
+
+// checkPropagate checks `target?`. The target must be a Result, and since
+// an Err is returned from the enclosing function unchanged, that function
+// must return a Result with the same error type.
+func (checker *Checker) checkPropagate(e *parser.PropagateExpression) Type {
+	target := checker.checkExpr(e.Target)
+	if checker.returns == nil {
+		checker.errors = append(checker.errors,
+			errorAt(CodeInvalid, e.Position, "the `?` operator can only be used inside a function"))
+		return checker.freshVar()
+	}
+	class, ok := checker.resultClass()
+	if !ok {
+		checker.errors = append(checker.errors,
+			errorAt(CodeUndefined, e.Position, "the `?` operator needs a Result type with Ok and Err; import result"))
+		return checker.freshVar()
+	}
+	value, errType, self := checker.instantiateResult(class)
+	if err := checker.unify(self, target); err != nil {
+		checker.errors = append(checker.errors,
+			errorAtNode(CodeMismatch, e.Target, "the `?` operator needs a Result, found %s", checker.apply(target)).
+				types(checker.apply(self), checker.apply(target)))
+		return value
+	}
+	_, early, returned := checker.instantiateResult(class)
+	checker.unify(early, errType)
+	if err := checker.unify(checker.returns, returned); err != nil {
+		expected, found := checker.apply(checker.returns), checker.apply(returned)
+		checker.errors = append(checker.errors,
+			errorAt(CodeMismatch, e.Position, "`?` returns %s early, but the enclosing function returns %s", found, expected).
+				types(expected, found))
+	}
+	return checker.apply(value)
+}
+
+// resultClass finds the Result type in scope, as declared by the result
+// module: a class with constructors Ok and Err of one field each.
+func (checker *Checker) resultClass() (*ClassDef, bool) {
+	class, ok := checker.env.getClass("Result")
+	if !ok {
+		return nil, false
+	}
+	for _, name := range []string{"Ok", "Err"} {
+		if ctor, ok := class.constructor(name); !ok || len(ctor.Fields) != 1 {
+			return nil, false
+		}
+	}
+	return class, true
+}
+
+// instantiateResult returns fresh Ok and Err payload types and the Result
+// type holding them.
+func (checker *Checker) instantiateResult(class *ClassDef) (Type, Type, Type) {
+	fresh := Subst{}
+	for _, id := range class.TypeParams {
+		fresh[id] = checker.freshVar()
+	}
+	ok, _ := class.constructor("Ok")
+	err, _ := class.constructor("Err")
+	return apply(ok.Fields[0], fresh), apply(err.Fields[0], fresh), apply(class.self(), fresh)
+}
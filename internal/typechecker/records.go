@@ -0,0 +1,303 @@
+package typechecker
+
+import (
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+	"sort"
+	"strings"
+)
+
+// declareRecord registers a record as a class with a single constructor
+// named after the record, so patterns, exhaustiveness and interfaces
+// treat it like any other product type.
+func (checker *Checker) declareRecord(e *parser.RecordDeclarationExpression) {
+	name := e.Name.Lexeme
+	if _, exists := checker.env.classes[name]; exists {
+		checker.errors = append(checker.errors, errorAt(CodeDuplicate, e.Name, "type %s is already declared", name))
+		return
+	}
+	class := &ClassDef{
+		Name:   name,
+		Module: checker.module,
+		Public: e.Public,
+		Record: true,
+	}
+	params := checker.bindTypeParameters(class, e.TypeParameters)
+	checker.env.setClass(name, class)
+
+	old, oldMode := checker.typeParams, checker.typeVarMode
+	checker.typeParams, checker.typeVarMode = params, classTypeVars
+	defer func() { checker.typeParams, checker.typeVarMode = old, oldMode }()
+	ctor := &ConstructorDef{
+		Name:   name,
+		Class:  name,
+		Public: e.Public,
+	}
+	for _, f := range e.Fields {
+		if _, dup := ctor.field(f.Name.Lexeme); dup {
+			checker.errors = append(checker.errors,
+				errorAt(CodeDuplicate, f.Name, "duplicate field %s in record %s", f.Name.Lexeme, name))
+			continue
+		}
+		ft := checker.resolveType(f.Type)
+		for _, id := range freeTypeVars(ft) {
+			if !contains(class.TypeParams, id) {
+				checker.errors = append(checker.errors,
+					errorAt(CodeInvalid, f.Name, "field %s.%s uses a type variable not declared by the record", name, f.Name.Lexeme))
+				break
+			}
+		}
+		ctor.FieldNames = append(ctor.FieldNames, f.Name.Lexeme)
+		ctor.Fields = append(ctor.Fields, ft)
+	}
+	class.Constructors = []*ConstructorDef{ctor}
+}
+
+func (ctor *ConstructorDef) field(name string) (int, bool) {
+	for i, f := range ctor.FieldNames {
+		if f == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// selectRecord picks the record type a set of field labels refers to. A
+// record declaring every label wins, preferring one that declares nothing
+// else; otherwise the candidates declaring the first label are returned so
+// the caller can report what is wrong.
+func (checker *Checker) selectRecord(labels []string) (*ClassDef, []*ClassDef) {
+	candidates := checker.env.recordsWithField(labels[0])
+	var full, exact []*ClassDef
+	for _, c := range candidates {
+		ctor := c.Constructors[0]
+		all := true
+		for _, l := range labels {
+			if _, ok := ctor.field(l); !ok {
+				all = false
+				break
+			}
+		}
+		if !all {
+			continue
+		}
+		full = append(full, c)
+		if len(ctor.FieldNames) == len(labels) {
+			exact = append(exact, c)
+		}
+	}
+	switch {
+	case len(full) == 1:
+		return full[0], nil
+	case len(exact) == 1:
+		return exact[0], nil
+	case len(full) > 1:
+		return nil, full
+	}
+	return nil, candidates
+}
+
+func (checker *Checker) resolveRecord(fields []lexer.Token) *ClassDef {
+	labels := make([]string, len(fields))
+	for i, f := range fields {
+		labels[i] = f.Lexeme
+	}
+	class, candidates := checker.selectRecord(labels)
+	switch {
+	case class != nil:
+		return class
+	case len(candidates) == 0:
+		checker.errors = append(checker.errors,
+			errorAt(CodeUndefined, fields[0], "no record type has a field %s", labels[0]))
+		return nil
+	case len(candidates) == 1:
+		return candidates[0]
+	}
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.Name
+	}
+	checker.errors = append(checker.errors,
+		errorAt(CodeAmbiguous, fields[0], "ambiguous record fields: could be any of %s", strings.Join(names, ", ")))
+	return nil
+}
+
+// recordOf returns the record class of t, if t is already known to be one.
+func (checker *Checker) recordOf(t Type) *ClassDef {
+	nt, ok := t.(*NamedType)
+	if !ok {
+		return nil
+	}
+	class, ok := checker.env.getClass(nt.Name)
+	if !ok || !class.Record {
+		return nil
+	}
+	return class
+}
+
+func (checker *Checker) instantiateRecord(class *ClassDef) (Type, Subst) {
+	fresh := Subst{}
+	for _, id := range class.TypeParams {
+		fresh[id] = checker.freshVar()
+	}
+	return apply(class.self(), fresh), fresh
+}
+
+func (checker *Checker) checkRecordExpression(e *parser.RecordExpression) Type {
+	labels := make([]lexer.Token, len(e.Fields))
+	for i, f := range e.Fields {
+		labels[i] = f.Name
+	}
+	var base Type
+	var class *ClassDef
+	if e.Base != nil {
+		base = checker.checkExpr(e.Base)
+		if _, ok := base.(*TypeVar); !ok {
+			class = checker.recordOf(base)
+			if class == nil {
+				checker.errors = append(checker.errors,
+					errorAtNode(CodeNotApplicable, e.Base, "cannot update a value of type %s: it is not a record", base))
+				return checker.freshVar()
+			}
+		}
+	}
+	if class == nil {
+		class = checker.resolveRecord(labels)
+	}
+	if class == nil {
+		return checker.freshVar()
+	}
+	self, fresh := checker.instantiateRecord(class)
+	if base != nil {
+		if err := checker.unify(self, base); err != nil {
+			checker.errors = append(checker.errors, checker.unifyError(e.Base, err, self, base))
+		}
+	}
+	ctor := class.Constructors[0]
+	seen := map[string]bool{}
+	for _, f := range e.Fields {
+		name := f.Name.Lexeme
+		value := checker.checkExpr(f.Value)
+		if seen[name] {
+			checker.errors = append(checker.errors,
+				errorAt(CodeDuplicate, f.Name, "field %s is set more than once", name))
+			continue
+		}
+		seen[name] = true
+		i, ok := ctor.field(name)
+		if !ok {
+			checker.errors = append(checker.errors,
+				errorAt(CodeUndefined, f.Name, "record %s has no field %s", class.Name, name))
+			continue
+		}
+		expected := apply(ctor.Fields[i], fresh)
+		if err := checker.unify(expected, value); err != nil {
+			checker.errors = append(checker.errors,
+				errorAtNode(CodeMismatch, f.Value, "field %s of record %s has type %s, found %s",
+					name, class.Name, checker.apply(expected), checker.apply(value)).
+					types(checker.apply(expected), checker.apply(value)))
+		}
+	}
+	if base == nil {
+		var missing []string
+		for _, name := range ctor.FieldNames {
+			if !seen[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			checker.errors = append(checker.errors,
+				errorAt(CodeMissingField, e.Position, "record %s is missing field(s) %s", class.Name, strings.Join(missing, ", ")))
+		}
+	}
+	return checker.apply(self)
+}
+
+func (checker *Checker) recordField(target Type, field lexer.Token) Type {
+	class := checker.recordOf(target)
+	if class == nil {
+		if _, ok := target.(*TypeVar); !ok {
+			checker.errors = append(checker.errors,
+				errorAt(CodeUndefined, field, "type %s has no field %s", target, field.Lexeme))
+			return checker.freshVar()
+		}
+		class = checker.resolveRecord([]lexer.Token{field})
+		if class == nil {
+			return checker.freshVar()
+		}
+	}
+	self, fresh := checker.instantiateRecord(class)
+	if err := checker.unify(self, target); err != nil {
+		checker.errors = append(checker.errors, errorAt(CodeMismatch, field, "%v", err).types(self, checker.apply(target)))
+	}
+	i, ok := class.Constructors[0].field(field.Lexeme)
+	if !ok {
+		checker.errors = append(checker.errors,
+			errorAt(CodeUndefined, field, "record %s has no field %s", class.Name, field.Lexeme))
+		return checker.freshVar()
+	}
+	return checker.apply(apply(class.Constructors[0].Fields[i], fresh))
+}
+
+func (checker *Checker) checkRecordPattern(p *parser.RecordPattern, expected Type, bindings map[string]Type) {
+	class := checker.recordOf(checker.apply(expected))
+	if class == nil {
+		labels := make([]lexer.Token, len(p.Fields))
+		for i, f := range p.Fields {
+			labels[i] = f.Name
+		}
+		if len(labels) == 0 {
+			checker.errors = append(checker.errors,
+				errorAt(CodeInvalid, p.Position, "record pattern needs at least one field"))
+			return
+		}
+		class = checker.resolveRecord(labels)
+		if class == nil {
+			return
+		}
+	}
+	checker.records[p] = class
+	self, fresh := checker.instantiateRecord(class)
+	if err := checker.unify(expected, self); err != nil {
+		checker.errors = append(checker.errors, checker.unifyError(p, err, checker.apply(expected), self))
+	}
+	ctor := class.Constructors[0]
+	seen := map[string]bool{}
+	for _, f := range p.Fields {
+		name := f.Name.Lexeme
+		if seen[name] {
+			checker.errors = append(checker.errors,
+				errorAt(CodeDuplicate, f.Name, "field %s appears more than once in the pattern", name))
+			continue
+		}
+		seen[name] = true
+		i, ok := ctor.field(name)
+		if !ok {
+			checker.errors = append(checker.errors,
+				errorAt(CodeUndefined, f.Name, "record %s has no field %s", class.Name, name))
+			continue
+		}
+		checker.checkPattern(f.Pattern, apply(ctor.Fields[i], fresh), bindings)
+	}
+}
+
+func (env *Env) recordsWithField(name string) []*ClassDef {
+	seen := map[string]bool{}
+	var out []*ClassDef
+	for e := env; e != nil; e = e.parent {
+		for className, c := range e.classes {
+			if seen[className] {
+				continue
+			}
+			seen[className] = true
+			if !c.Record {
+				continue
+			}
+			if _, ok := c.Constructors[0].field(name); ok {
+				out = append(out, c)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,106 @@
+package typechecker
+
+import (
+	"fmt"
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+	"sort"
+	"strings"
+)
+
+// Result is what Check learns about a program. Types are final: every
+// type variable solved anywhere in the program is substituted.
+type Result struct {
+	// Types holds the type of every checked expression.
+	Types map[parser.Expression]Type
+	// Names holds the type of every name the program binds (declarations,
+	// parameters and pattern variables), keyed by the token binding it.
+	Names map[lexer.Token]Type
+	// Env holds the schemes of the program's top-level environment.
+	Env map[string]*Scheme
+	// Instances holds the type class instances each operator or call of a
+	// constrained function needs, keyed by its token. A type that is still
+	// a variable is one of the enclosing binding's own constraints, which
+	// its callers resolve in turn.
+	Instances map[lexer.Token][]Constraint
+	Matches   []CompiledMatch
+	Errors    []error
+}
+
+func newResult() *Result {
+	return &Result{
+		Types:     map[parser.Expression]Type{},
+		Names:     map[lexer.Token]Type{},
+		Instances: map[lexer.Token][]Constraint{},
+	}
+}
+
+func (checker *Checker) recordType(e parser.Expression, t Type) {
+	if checker.result != nil {
+		checker.result.Types[e] = t
+	}
+}
+
+func (checker *Checker) recordName(name lexer.Token, t Type) {
+	if checker.result != nil {
+		checker.result.Names[name] = t
+	}
+}
+
+func (checker *Checker) recordInstance(at lexer.Token, c Constraint) {
+	if checker.result != nil {
+		checker.result.Instances[at] = append(checker.result.Instances[at], c)
+	}
+}
+
+func (checker *Checker) finishResult() {
+	r := checker.result
+	for e, t := range r.Types {
+		r.Types[e] = checker.apply(t)
+	}
+	for name, t := range r.Names {
+		r.Names[name] = checker.apply(t)
+	}
+	for at, cs := range r.Instances {
+		for i, c := range cs {
+			cs[i] = Constraint{Class: c.Class, Type: checker.apply(c.Type)}
+		}
+		r.Instances[at] = cs
+	}
+	r.Env = checker.env.values
+	r.Errors = checker.errors
+}
+
+// DumpTypes lists every name the program binds in source order with its
+// type. Top-level declarations, including the members of recursive
+// groups, show their generalized scheme.
+func DumpTypes(program *parser.Program, r *Result) string {
+	topLevel := map[lexer.Token]bool{}
+	for _, e := range declarations(program.Expressions) {
+		switch d := e.(type) {
+		case *parser.VariableDeclarationExpression:
+			topLevel[d.Name] = true
+		case *parser.FunctionDeclarationExpression:
+			topLevel[d.Name] = true
+		}
+	}
+	names := make([]lexer.Token, 0, len(r.Names))
+	for name := range r.Names {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Line != names[j].Line {
+			return names[i].Line < names[j].Line
+		}
+		return names[i].Column < names[j].Column
+	})
+	var out strings.Builder
+	for _, name := range names {
+		var t fmt.Stringer = r.Names[name]
+		if s, ok := r.Env[name.Lexeme]; ok && topLevel[name] {
+			t = s
+		}
+		fmt.Fprintf(&out, "%d:%d %s: %s\n", name.Line, name.Column, name.Lexeme, t)
+	}
+	return out.String()
+}
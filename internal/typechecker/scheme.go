@@ -1,8 +1,14 @@
 package typechecker
 
+import (
+	"lunno/internal/parser"
+	"strings"
+)
+
 type Scheme struct {
-	TypeVars []int
-	Type     Type
+	TypeVars    []int
+	Constraints []Constraint
+	Type        Type
 }
 
 func contains(slice []int, val int) bool {
@@ -14,9 +20,20 @@ func contains(slice []int, val int) bool {
 	return false
 }
 
-func generalize(env *Env, typ Type) *Scheme {
+// generalize quantifies typ over its variables that are not free in env.
+// The environment is read through s, since its types may mention
+// variables that have been solved since they were bound.
+//
+// Only the type of a syntactic value is generalized. Anything else may
+// create a reference cell when it runs, and `let r = builtin_ref([])`
+// must not give r the type Ref[[T]] for every T, or one use could store
+// [1] and another read it back as [string].
+func generalize(env *Env, typ Type, s Subst, value bool) *Scheme {
+	if !value {
+		return &Scheme{Type: typ}
+	}
 	free := freeTypeVars(typ)
-	envFree := envFreeTypeVars(env)
+	envFree := envFreeTypeVars(env, s)
 	var quantified []int
 	for _, v := range free {
 		if !contains(envFree, v) {
@@ -29,6 +46,71 @@ func generalize(env *Env, typ Type) *Scheme {
 	}
 }
 
+// isValue reports whether e is a syntactic value: a literal, a name, a
+// function, or a constructor applied to values. Evaluating one cannot
+// allocate, so its type is safe to generalize.
+func (checker *Checker) isValue(e parser.Node) bool {
+	switch e := e.(type) {
+	case *parser.IntegerLiteral, *parser.FloatLiteral, *parser.StringLiteral, *parser.CharacterLiteral,
+		*parser.BooleanLiteral, *parser.UnitLiteral, *parser.Identifier, *parser.FunctionLiteralExpression:
+		return true
+	case *parser.FieldAccessExpression:
+		return checker.isValue(e.Target)
+	case *parser.ListExpression:
+		return checker.allValues(e.Elements)
+	case *parser.TupleExpression:
+		return checker.allValues(e.Elements)
+	case *parser.RecordExpression:
+		if e.Base != nil && !checker.isValue(e.Base) {
+			return false
+		}
+		for _, f := range e.Fields {
+			if !checker.isValue(f.Value) {
+				return false
+			}
+		}
+		return true
+	case *parser.CallExpression:
+		return checker.isConstructor(e.Callee) && checker.allValues(e.Arguments)
+	}
+	return false
+}
+
+func (checker *Checker) allValues(exprs []parser.Expression) bool {
+	for _, e := range exprs {
+		if !checker.isValue(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// isConstructor reports whether callee resolves to a constructor, bare
+// or qualified as `Option.Some`. A capitalized name bound by a let is an
+// ordinary function and may allocate.
+func (checker *Checker) isConstructor(callee parser.Expression) bool {
+	switch c := callee.(type) {
+	case *parser.Identifier:
+		s, ok := checker.env.get(c.Name)
+		return ok && checker.constructors[s]
+	case *parser.FieldAccessExpression:
+		id, ok := c.Target.(*parser.Identifier)
+		if !ok {
+			return false
+		}
+		if _, shadowed := checker.env.get(id.Name); shadowed {
+			return false
+		}
+		class, ok := checker.env.getClass(id.Name)
+		if !ok {
+			return false
+		}
+		_, ok = class.constructor(c.Field.Lexeme)
+		return ok
+	}
+	return false
+}
+
 func instantiate(s *Scheme, checker *Checker) Type {
 	subst := Subst{}
 	for _, id := range s.TypeVars {
@@ -37,39 +119,69 @@ func instantiate(s *Scheme, checker *Checker) Type {
 	return apply(s.Type, subst)
 }
 
+// freeTypeVars lists the variables in t in order of first appearance.
 func freeTypeVars(t Type) []int {
-	set := map[int]struct{}{}
+	var res []int
 	var collect func(Type)
 	collect = func(tt Type) {
 		switch ty := tt.(type) {
 		case *TypeVar:
-			set[ty.ID] = struct{}{}
+			if !contains(res, ty.ID) {
+				res = append(res, ty.ID)
+			}
 		case *ListType:
 			collect(ty.Element)
+		case *TupleType:
+			for _, e := range ty.Elements {
+				collect(e)
+			}
 		case *FunctionType:
 			for _, p := range ty.Parameters {
 				collect(p)
 			}
 			collect(ty.Return)
+		case *NamedType:
+			for _, a := range ty.Arguments {
+				collect(a)
+			}
 		}
 	}
 	collect(t)
-	var res []int
-	for id := range set {
-		res = append(res, id)
-	}
 	return res
 }
 
-func envFreeTypeVars(env *Env) []int {
+// String prints s with its quantified variables renumbered from zero in
+// order of appearance, so that equal schemes print the same.
+func (s *Scheme) String() string {
+	ids := map[int]int{}
+	for _, id := range freeTypeVars(s.Type) {
+		if contains(s.TypeVars, id) {
+			ids[id] = len(ids)
+		}
+	}
+	var out strings.Builder
+	for i, c := range s.Constraints {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(c.Class + " " + rename(c.Type, ids).String())
+	}
+	if len(s.Constraints) > 0 {
+		out.WriteString(" => ")
+	}
+	out.WriteString(rename(s.Type, ids).String())
+	return out.String()
+}
+
+func envFreeTypeVars(env *Env, s Subst) []int {
 	set := map[int]struct{}{}
 	for _, t := range env.values {
-		for _, id := range freeTypeVarsScheme(t) {
+		for _, id := range freeTypeVarsScheme(t, s) {
 			set[id] = struct{}{}
 		}
 	}
 	if env.parent != nil {
-		for _, id := range envFreeTypeVars(env.parent) {
+		for _, id := range envFreeTypeVars(env.parent, s) {
 			set[id] = struct{}{}
 		}
 	}
@@ -81,10 +193,10 @@ func envFreeTypeVars(env *Env) []int {
 	return res
 }
 
-func freeTypeVarsScheme(scheme interface{}) []int {
+func freeTypeVarsScheme(scheme interface{}, subst Subst) []int {
 	switch s := scheme.(type) {
 	case *Scheme:
-		ft := freeTypeVars(s.Type)
+		ft := freeTypeVars(apply(s.Type, subst))
 		quantified := map[int]struct{}{}
 		for _, id := range s.TypeVars {
 			quantified[id] = struct{}{}
@@ -97,6 +209,6 @@ func freeTypeVarsScheme(scheme interface{}) []int {
 		}
 		return res
 	default:
-		return freeTypeVars(s.(Type))
+		return freeTypeVars(apply(s.(Type), subst))
 	}
 }
@@ -2,21 +2,105 @@ package typechecker
 
 import (
 	"fmt"
+	"lunno/internal/lexer"
 	"lunno/internal/parser"
+	"strings"
 )
 
-func Check(parser *parser.Program) []error {
-	checker := &Checker{
-		env: newEnv(nil),
+func Check(program *parser.Program, config Config) *Result {
+	checker := newChecker(newLoader(config))
+	checker.result = newResult()
+	checker.checkProgram(program.Expressions)
+	checker.finishResult()
+	return checker.result
+}
+
+// checkProgram checks the top level of a module. Imports and type
+// declarations come first, then every annotated declaration is bound to
+// its signature, so a function may call one defined further down.
+func (checker *Checker) checkProgram(exprs []parser.Expression) {
+	var rest []parser.Expression
+	for _, e := range exprs {
+		switch e.(type) {
+		case *parser.ImportExpression, *parser.ClassDeclarationExpression, *parser.RecordDeclarationExpression,
+			*parser.TypeAliasDeclarationExpression, *parser.NewtypeDeclarationExpression:
+			checker.checkExpr(e)
+		default:
+			rest = append(rest, e)
+		}
+	}
+	errCount := len(checker.errors)
+	for _, e := range declarations(rest) {
+		switch d := e.(type) {
+		case *parser.VariableDeclarationExpression:
+			checker.declareSignature(d.Name.Lexeme, d.Type)
+		case *parser.FunctionDeclarationExpression:
+			checker.declareSignature(d.Name.Lexeme, d.Signature)
+		}
 	}
-	registerBuiltins(checker.env)
-	for _, e := range parser.Expressions {
+	// Signature errors are reported when the declaration itself is checked.
+	checker.errors = checker.errors[:errCount]
+	for _, e := range rest {
 		checker.checkExpr(e)
 	}
-	return checker.errors
+	checker.solveRemaining()
+	checker.reportHoles()
+}
+
+// declarations flattens recursive groups into the declarations they hold.
+func declarations(exprs []parser.Expression) []parser.Expression {
+	var out []parser.Expression
+	for _, e := range exprs {
+		if group, ok := e.(*parser.RecursiveGroupExpression); ok {
+			out = append(out, group.Declarations...)
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
 }
 
+func (checker *Checker) declareSignature(name string, sig parser.TypeNode) {
+	if sig == nil {
+		return
+	}
+	outer := checker.enterTypeScope()
+	t := checker.resolveType(sig)
+	checker.typeParams = outer
+	checker.env.set(name, checker.generalize(t, true))
+}
+
+func newChecker(loader *loader) *Checker {
+	checker := &Checker{
+		env:          newEnv(nil),
+		loader:       loader,
+		private:      map[string]string{},
+		imports:      map[string]string{},
+		records:      map[*parser.RecordPattern]*ClassDef{},
+		constructors: map[*Scheme]bool{},
+		importedFrom: map[*Scheme][]string{},
+		aliases:      map[parser.TypeNode]*aliasType{},
+		subst:        Subst{},
+	}
+	registerBuiltins(checker)
+	checker.env = newEnv(checker.env)
+	return checker
+}
+
+// checkExpr infers the type of expr with every solved variable in it
+// substituted, so callers can inspect its shape directly.
 func (checker *Checker) checkExpr(expr parser.Expression) Type {
+	t := checker.apply(checker.inferExpr(expr))
+	if expr != nil {
+		checker.recordType(expr, t)
+	}
+	return t
+}
+
+func (checker *Checker) inferExpr(expr parser.Expression) Type {
+	if expr == nil {
+		return checker.freshVar()
+	}
 	switch e := expr.(type) {
 	case *parser.IntegerLiteral:
 		return &IntType{}
@@ -30,54 +114,237 @@ func (checker *Checker) checkExpr(expr parser.Expression) Type {
 		return &CharType{}
 	case *parser.UnitLiteral:
 		return &UnitType{}
+	case *parser.HoleExpression:
+		return checker.checkHole(e)
 	case *parser.Identifier:
 		if s, ok := checker.env.get(e.Name); ok {
-			return instantiate(s, checker)
+			if from := checker.importedFrom[s]; len(from) > 1 {
+				checker.errors = append(checker.errors, errorAt(CodeAmbiguous, e.Position,
+					"%s is imported from more than one module: %s", e.Name, strings.Join(from, ", ")))
+				return checker.freshVar()
+			}
+			return checker.instantiateAt(s, e.Position)
+		}
+		if module, ok := checker.private[e.Name]; ok {
+			checker.errors = append(checker.errors,
+				errorAt(CodePrivate, e.Position, "%s is private to module %s", e.Name, module))
+			return checker.freshVar()
 		}
-		checker.errors = append(checker.errors, fmt.Errorf("undefined identifier %s", e.Name))
+		checker.errors = append(checker.errors, errorAt(CodeUndefined, e.Position, "undefined identifier %s", e.Name))
 		return checker.freshVar()
 	case *parser.ListExpression:
 		elem := checker.freshVar()
-		subst := Subst{}
 		for _, el := range e.Elements {
 			t := checker.checkExpr(el)
-			if err := unify(elem, t, subst); err != nil {
-				checker.errors = append(checker.errors, err)
+			if err := checker.unify(elem, t); err != nil {
+				checker.errors = append(checker.errors, checker.unifyError(el, err, elem, t).
+					related(parser.StartToken(e.Elements[0]), "the list's element type comes from here"))
 			}
 		}
-		return apply(&ListType{
+		return &ListType{
 			Element: elem,
-		}, subst)
+		}
+	case *parser.TupleExpression:
+		elems := make([]Type, len(e.Elements))
+		for i, el := range e.Elements {
+			elems[i] = checker.checkExpr(el)
+		}
+		return &TupleType{Elements: elems}
 	case *parser.FunctionLiteralExpression:
 		return checker.checkFunctionLiteral(e, nil)
 	case *parser.VariableDeclarationExpression:
+		checker.checkBindings([]parser.Expression{e}, e.Recursive)
+		return &UnitType{}
+	case *parser.FunctionDeclarationExpression:
+		checker.checkBindings([]parser.Expression{e}, e.Recursive)
+		return &UnitType{}
+	case *parser.RecursiveGroupExpression:
+		checker.checkBindings(e.Declarations, true)
+		return &UnitType{}
+	case *parser.DestructuringDeclarationExpression:
+		outer := checker.enterTypeScope()
+		var declType Type
+		if e.Type != nil {
+			declType = checker.resolveType(e.Type)
+		}
 		valType := checker.checkExpr(e.Value)
-		declType := checker.resolveType(e.Type)
-		subst := Subst{}
-		if err := unify(declType, valType, subst); err != nil {
-			checker.errors = append(checker.errors, err)
+		if declType != nil {
+			if err := checker.unify(declType, valType); err != nil {
+				checker.errors = append(checker.errors, checker.annotationError(e.Value, err, e.Type, declType, valType))
+			}
+		}
+		if e.Else != nil {
+			checker.checkLetElse(e.Else)
+		}
+		errCount := len(checker.errors)
+		bindings := map[string]Type{}
+		checker.checkPattern(e.Pattern, valType, bindings)
+		if len(checker.errors) == errCount {
+			checker.checkLetPattern(e, checker.apply(valType))
 		}
-		generalized := generalize(checker.env, apply(declType, subst))
-		checker.env.set(e.Name.Lexeme, generalized)
+		if !checker.isValue(e.Value) {
+			checker.requireMonomorphic(outer, e.Position, "the destructuring let")
+		}
+		checker.leaveTypeScope(outer, e.Position, "the destructuring let")
+		for name, t := range bindings {
+			checker.env.set(name, checker.generalize(t, checker.isValue(e.Value)))
+		}
+		return &UnitType{}
+	case *parser.ImportExpression:
+		checker.importModule(e)
+		return &UnitType{}
+	case *parser.ClassDeclarationExpression:
+		checker.declareClass(e)
 		return &UnitType{}
+	case *parser.RecordDeclarationExpression:
+		checker.declareRecord(e)
+		return &UnitType{}
+	case *parser.TypeAliasDeclarationExpression:
+		checker.declareAlias(e)
+		return &UnitType{}
+	case *parser.NewtypeDeclarationExpression:
+		checker.declareNewtype(e)
+		return &UnitType{}
+	case *parser.RecordExpression:
+		return checker.checkRecordExpression(e)
+	case *parser.LazyExpression:
+		return lazyOf(checker.checkExpr(e.Body))
+	case *parser.TryExpression:
+		return checker.checkTry(e)
+	case *parser.PropagateExpression:
+		return checker.checkPropagate(e)
+	case *parser.FieldAccessExpression:
+		return checker.checkFieldAccess(e)
+	case *parser.MatchExpression:
+		return checker.checkMatch(e)
+	case *parser.InfixExpression:
+		return checker.checkInfix(e)
+	case *parser.PrefixExpression:
+		return checker.checkPrefix(e)
+	case *parser.CallExpression:
+		return checker.checkCall(e)
+	case *parser.IndexExpression:
+		return checker.checkIndex(e)
+	case *parser.SliceExpression:
+		return checker.checkSlice(e)
+	case *parser.BlockExpression:
+		return checker.checkBlock(e)
 	case *parser.IfExpression:
 		cond := checker.checkExpr(e.Condition)
-		if _, ok := cond.(*BoolType); !ok {
+		if err := checker.unify(cond, &BoolType{}); err != nil {
 			checker.errors = append(checker.errors,
-				fmt.Errorf("if condition must be bool"))
+				errorAtNode(CodeMismatch, e.Condition, "if condition must be bool").types(&BoolType{}, cond))
 		}
 		t1 := checker.checkExpr(e.Then)
 		t2 := checker.checkExpr(e.Else)
-		subst := Subst{}
-		if err := unify(t1, t2, subst); err != nil {
-			checker.errors = append(checker.errors, err)
+		if err := checker.unify(t1, t2); err != nil {
+			checker.errors = append(checker.errors, checker.unifyError(e.Else, err, t1, t2).
+				related(parser.StartToken(e.Then), "the then branch has type %s", checker.apply(t1)))
 		}
-		return apply(t1, subst)
+		return t1
 	}
+	checker.errors = append(checker.errors, errorAtNode(CodeInvalid, expr, "cannot typecheck %s", expr.NodeType()))
 	return checker.freshVar()
 }
 
+// binding is a let declaration split into its declared type, which is
+// known before any value is checked, and the check of its value.
+type binding struct {
+	name       lexer.Token
+	annotation parser.TypeNode
+	value      parser.Node
+	declared   Type
+	check      func() Type
+	// scope holds the type variables named by the annotation.
+	scope map[string]Type
+}
+
+func (checker *Checker) newBinding(decl parser.Expression) *binding {
+	switch d := decl.(type) {
+	case *parser.VariableDeclarationExpression:
+		b := &binding{name: d.Name, annotation: d.Type, value: d.Value, declared: checker.freshVar()}
+		if d.Type != nil {
+			b.declared = checker.resolveType(d.Type)
+		}
+		b.check = func() Type { return checker.checkExpr(d.Value) }
+		return b
+	case *parser.FunctionDeclarationExpression:
+		b := &binding{name: d.Name, annotation: d.Signature, value: d.Function, declared: checker.freshVar()}
+		var declared *FunctionType
+		if d.Signature != nil {
+			ft, ok := checker.resolveType(d.Signature).(*FunctionType)
+			if !ok {
+				checker.errors = append(checker.errors,
+					errorAtNode(CodeInvalid, d.Signature, "signature of %s must be a function type", d.Name.Lexeme))
+			} else {
+				declared, b.declared = ft, ft
+			}
+		}
+		b.check = func() Type { return checker.checkFunctionLiteral(d.Function, declared) }
+		return b
+	}
+	return nil
+}
+
+// checkBindings checks a let declaration, or every declaration of a
+// `let rec ... and ...` group. Recursive names are bound monomorphically
+// while the values are checked, so each value may refer to all of them,
+// and are generalized only once the whole group is checked.
+func (checker *Checker) checkBindings(decls []parser.Expression, recursive bool) {
+	bindings := make([]*binding, len(decls))
+	for i, d := range decls {
+		outer := checker.enterTypeScope()
+		bindings[i] = checker.newBinding(d)
+		bindings[i].scope = checker.typeParams
+		checker.typeParams = outer
+	}
+	old := checker.env
+	if recursive {
+		checker.env = newEnv(old)
+		for _, b := range bindings {
+			checker.env.set(b.name.Lexeme, &Scheme{Type: b.declared})
+		}
+	}
+	outer := checker.typeParams
+	for _, b := range bindings {
+		checker.typeParams = b.scope
+		self, bound := checker.env.values[b.name.Lexeme]
+		// A top-level name is bound before its value is checked only by
+		// its signature, see declareSignature.
+		topLevel := checker.env.parent != nil && checker.env.parent.parent == nil
+		if bound && (recursive || topLevel && b.annotation != nil) {
+			checker.declaring = append(checker.declaring, self)
+		} else {
+			self = nil
+		}
+		t := b.check()
+		if self != nil {
+			checker.declaring = checker.declaring[:len(checker.declaring)-1]
+		}
+		if err := checker.unify(b.declared, t); err != nil {
+			checker.errors = append(checker.errors, checker.annotationError(b.value, err, b.annotation, b.declared, t))
+		}
+	}
+	checker.env = old
+	for _, b := range bindings {
+		checker.typeParams = b.scope
+		checker.env.declare(b.name)
+		if !checker.isValue(b.value) {
+			checker.requireMonomorphic(outer, b.name, b.name.Lexeme)
+		}
+		checker.leaveTypeScope(outer, b.name, b.name.Lexeme)
+		checker.recordName(b.name, b.declared)
+		checker.env.set(b.name.Lexeme, checker.generalize(b.declared, checker.isValue(b.value)))
+	}
+}
+
 func (checker *Checker) checkFunctionLiteral(e *parser.FunctionLiteralExpression, declaredType *FunctionType) Type {
+	if declaredType != nil && len(declaredType.Parameters) != len(e.Parameters) {
+		checker.errors = append(checker.errors,
+			errorAt(CodeArity, e.Position, "function has %d parameter(s) but its signature declares %d",
+				len(e.Parameters), len(declaredType.Parameters)))
+		declaredType = nil
+	}
 	fnEnv := newEnv(checker.env)
 	params := make([]Type, len(e.Parameters))
 	for i, p := range e.Parameters {
@@ -90,22 +357,45 @@ func (checker *Checker) checkFunctionLiteral(e *parser.FunctionLiteralExpression
 			pt = checker.freshVar()
 		}
 		params[i] = pt
-		fnEnv.set(p.Name.Lexeme, generalize(checker.env, pt))
+		checker.recordName(p.Name, pt)
+		fnEnv.set(p.Name.Lexeme, &Scheme{Type: pt})
 	}
-	old := checker.env
-	checker.env = fnEnv
-	body := checker.checkExpr(e.Body)
-	checker.env = old
-	subst := Subst{}
-	var retType Type = &UnitType{}
+	var ret Type = checker.freshVar()
 	if declaredType != nil {
-		retType = declaredType.Return
+		ret = declaredType.Return
 	}
-	if err := unify(body, retType, subst); err != nil {
-		checker.errors = append(checker.errors, err)
+	old, oldReturns := checker.env, checker.returns
+	checker.env, checker.returns = fnEnv, ret
+	body := checker.checkExpr(e.Body)
+	checker.env, checker.returns = old, oldReturns
+	if err := checker.unify(ret, body); err != nil {
+		checker.errors = append(checker.errors, checker.unifyError(resultNode(e.Body), err, checker.apply(ret), body))
 	}
 	return &FunctionType{
 		Parameters: params,
-		Return:     apply(body, subst),
+		Return:     checker.apply(ret),
+	}
+}
+
+// annotationError reports that a value does not match its declaration's
+// annotation, pointing back at the annotation.
+func (checker *Checker) annotationError(value parser.Node, err error, annotation parser.TypeNode, expected, found Type) *TypeError {
+	te := checker.unifyError(value, err, expected, found)
+	if annotation == nil {
+		return te
+	}
+	if alias, ok := checker.aliases[annotation]; ok && te.Code == CodeMismatch {
+		te.Expected = te.names.show(checker.applyAlias(alias))
+		te.Message = fmt.Sprintf("expected %s, found %s: %s", te.Expected, te.Found, te.names.sprintf("%v", err))
+	}
+	return te.related(parser.StartToken(annotation), "expected because of this annotation")
+}
+
+// resultNode returns the expression whose value e produces, so errors
+// about a block's type point at its last expression.
+func resultNode(e parser.Expression) parser.Expression {
+	if b, ok := e.(*parser.BlockExpression); ok && len(b.Expressions) > 0 {
+		return resultNode(b.Expressions[len(b.Expressions)-1])
 	}
+	return e
 }
@@ -0,0 +1,1411 @@
+package typechecker_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+	"lunno/internal/typechecker"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func checkSource(t *testing.T, src string) []error {
+	t.Helper()
+	return checkWith(t, src, typechecker.Config{})
+}
+
+// writeModules writes each module's source to name.ln in a fresh
+// directory and returns a config that searches it.
+func writeModules(t *testing.T, modules map[string]string) typechecker.Config {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range modules {
+		if err := os.WriteFile(filepath.Join(dir, name+".ln"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return typechecker.Config{SearchPaths: []string{dir}}
+}
+
+func checkWith(t *testing.T, src string, config typechecker.Config) []error {
+	t.Helper()
+	return checkResult(t, src, config).Errors
+}
+
+func checkResult(t *testing.T, src string, config typechecker.Config) *typechecker.Result {
+	t.Helper()
+	lx, tokens, err := lexer.Tokenize(src, "test.ln")
+	if err != nil {
+		t.Fatalf("unexpected lexing error: %v", err)
+	}
+	program, errs := parser.ParseProgram(tokens, lx)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	return typechecker.Check(program, config)
+}
+
+func expectError(t *testing.T, errs []error, want string) {
+	t.Helper()
+	if want == "" {
+		if len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		return
+	}
+	for _, err := range errs {
+		if strings.Contains(err.Error(), want) {
+			return
+		}
+	}
+	t.Fatalf("expected error containing %q, got %v", want, errs)
+}
+
+type checkCase struct {
+	name    string
+	input   string
+	wantErr string
+}
+
+func runChecks(t *testing.T, tests []checkCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, checkSource(t, tt.input), tt.wantErr)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []checkCase{
+		{
+			name:  "literal arms",
+			input: `let s: string = match 1 with { | 0 -> "zero" | _ -> "many" }`,
+		},
+		{
+			name:    "arm type mismatch",
+			input:   `match 1 with { | 0 -> "zero" | _ -> 1 }`,
+			wantErr: "test.ln:1:37: match arm has type int, but earlier arms have type string",
+		},
+		{
+			name:    "guard must be bool",
+			input:   `match 1 with { | x when 1 -> x }`,
+			wantErr: "match guard must be bool, found int",
+		},
+		{
+			name:    "list pattern bindings",
+			input:   `let s: string = match [1, 2] with { | [a, b] -> a | _ -> 0 }`,
+			wantErr: "type mismatch: string vs int",
+		},
+		{
+			name: "constructor patterns",
+			input: `class Shape { Circle(radius: float) Square(float) Point }
+let r: float = match Shape.Point with { | Circle(r) -> r | Shape.Square(s) -> s | Point -> 0.0 }`,
+		},
+		{
+			name: "constructor arity",
+			input: `class Shape { Circle(radius: float) Point }
+match Point with { | Circle(a, b) -> a }`,
+			wantErr: "constructor Circle expects 1 argument(s), got 2",
+		},
+		{
+			name:    "or-pattern bindings",
+			input:   `match [1] with { | [x] | [] -> 0 }`,
+			wantErr: "all alternatives of an or-pattern must bind the same variables",
+		},
+		{
+			name:  "nil matches strings",
+			input: `let s: string = match "msg" with { | nil -> "empty" | m -> m }`,
+		},
+		{
+			name: "rest bindings",
+			input: `let rest: [int] = match [1, 2] with { | [] -> [] | [x, ...xs] -> xs }
+let n: int = match [1] with { | [first, second, ...] -> second | _ -> 0 }`,
+		},
+		{
+			name:    "rest is a list",
+			input:   `match [1] with { | [x, ...xs] -> xs | _ -> 0 }`,
+			wantErr: "match arm has type int, but earlier arms have type list(int)",
+		},
+		{
+			name: "tuple destructuring",
+			input: `let (n, s) = (1, "a")
+let m: int = n`,
+		},
+		{
+			name:    "tuple arity",
+			input:   `let p: (int, string) = (1, "a", true)`,
+			wantErr: "type mismatch",
+		},
+	}
+
+	runChecks(t, tests)
+}
+
+func TestConstructorNames(t *testing.T) {
+	tests := []checkCase{
+		{
+			name: "constructor declared by two classes",
+			input: `class A { X(int) }
+class B { X(string) }`,
+			wantErr: "test.ln:2:11: constructor X is already declared by class A",
+		},
+		{
+			name: "local class shadows a builtin one",
+			input: `class Alarm { Panic(string) }
+let f = fn(a) { match a with { | Panic(s) -> s } }`,
+		},
+	}
+	runChecks(t, tests)
+
+	config := writeModules(t, map[string]string{
+		"a": "pub class A { pub X(int) pub Y }\n",
+		"b": "pub class B { pub X(string) }\n",
+	})
+	imports := "import a\nimport b\n"
+	// The classes come from a map, so check the bare name fails the same
+	// way every time rather than picking either class.
+	for i := 0; i < 20; i++ {
+		errs := checkWith(t, imports+`let f = fn(v) { match v with { | X(n) -> n | Y -> 0 } }`, config)
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "ambiguous constructor X: write one of A.X, B.X") {
+			t.Fatalf("run %d: expected one ambiguity error, got %v", i, errs)
+		}
+	}
+	expectError(t, checkWith(t, imports+`let f = fn(v) { match v with { | A.X(n) -> n | A.Y -> 0 } }
+let g = fn(v) { match v with { | B.X(s) -> s } }`, config), "")
+}
+
+func TestPrivateNames(t *testing.T) {
+	config := writeModules(t, map[string]string{"shapes": `pub class Shape { pub Circle(int) Hidden(int) }
+class Secret { Hush }
+let secret = 1
+pub let visible = 2
+`})
+	const imports = "import shapes\n"
+	tests := []checkCase{
+		{
+			name: "public names are imported",
+			input: `let x: int = visible
+let s: Shape = Circle(x)`,
+		},
+		{
+			name:    "private value",
+			input:   `let x = secret`,
+			wantErr: "test.ln:2:9: secret is private to module shapes",
+		},
+		{
+			name:    "private constructor",
+			input:   `let x = Hidden(1)`,
+			wantErr: "test.ln:2:9: Hidden is private to module shapes",
+		},
+		{
+			name:    "qualified private constructor",
+			input:   `let x = Shape.Hidden(1)`,
+			wantErr: "constructor Shape.Hidden is private to module shapes",
+		},
+		{
+			name:    "constructor of a private class",
+			input:   `let x = Hush`,
+			wantErr: "test.ln:2:9: Hush is private to module shapes",
+		},
+		{
+			name:    "private class",
+			input:   `let f = fn(s: Secret) { 1 }`,
+			wantErr: "unknown type Secret",
+		},
+		{
+			name:    "matching on a private constructor",
+			input:   `let f = fn(s: Shape) { match s with { | Circle(n) -> n | Hidden(n) -> n } }`,
+			wantErr: "constructor Shape.Hidden is private to module shapes",
+		},
+		{
+			name:    "matching on a qualified private constructor",
+			input:   `let f = fn(s: Shape) { match s with { | Shape.Circle(n) -> n | Shape.Hidden(n) -> n } }`,
+			wantErr: "constructor Shape.Hidden is private to module shapes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, checkWith(t, imports+tt.input, config), tt.wantErr)
+		})
+	}
+}
+
+func TestImportClashes(t *testing.T) {
+	stdlib := typechecker.Config{SearchPaths: []string{filepath.Join("..", "..", "pkg", "stdlib")}}
+	expectError(t, checkWith(t, `import list
+import seq
+let xs: [int] = filter(fn(x) { x > 1 }, map(fn(x) { x + 1 }, [1, 2]))
+let ys: [int] = to_list(seq_filter(fn(x) { x > 1 }, seq_map(fn(x) { x * 2 }, range(0, 3))))`, stdlib), "")
+
+	config := writeModules(t, map[string]string{
+		"a": "pub let f = fn(x: int) { x }\npub let g = 1\n",
+		"b": "pub let f = fn(s: string) { s }\n",
+	})
+	const imports = "import a\nimport b\n"
+	tests := []checkCase{
+		{
+			name:    "a name two imports bind",
+			input:   `let n = f(1)`,
+			wantErr: "test.ln:3:9: f is imported from more than one module: a, b",
+		},
+		{
+			name:  "other names are unaffected",
+			input: `let n: int = g`,
+		},
+		{
+			name: "a local binding shadows both",
+			input: `let f = fn(b: bool) { b }
+let t: bool = f(true)`,
+		},
+		{
+			name: "importing a module twice",
+			input: `import a
+let n: int = f(1)`,
+			wantErr: "f is imported from more than one module: a, b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, checkWith(t, imports+tt.input, config), tt.wantErr)
+		})
+	}
+	expectError(t, checkWith(t, "import a\nimport a\nlet n: int = f(1)", config), "")
+}
+
+func TestExhaustiveness(t *testing.T) {
+	tests := []checkCase{
+		{
+			name:  "wildcard covers everything",
+			input: `match 1 with { | 0 -> 1 | _ -> 2 }`,
+		},
+		{
+			name:    "missing list length",
+			input:   `match [1] with { | nil -> 0 | [a] -> a | [a, b] -> b }`,
+			wantErr: "non-exhaustive match: `[_, _, _]` not covered",
+		},
+		{
+			name:    "missing literal",
+			input:   `match 1 with { | 0 -> 1 | 1 -> 2 }`,
+			wantErr: "non-exhaustive match: `_` not covered",
+		},
+		{
+			name:    "missing bool",
+			input:   `match true with { | true -> 1 }`,
+			wantErr: "non-exhaustive match: `false` not covered",
+		},
+		{
+			name:    "missing tuple component",
+			input:   `match (true, 1) with { | (true, n) -> n }`,
+			wantErr: "non-exhaustive match: `(false, _)` not covered",
+		},
+		{
+			name: "missing nested constructor",
+			input: `class Opt[T] { Some(value: T) None }
+match None with { | Some(true) -> 1 | None -> 0 }`,
+			wantErr: "non-exhaustive match: `Some(false)` not covered",
+		},
+		{
+			name: "all constructors",
+			input: `class Opt[T] { Some(value: T) None }
+match None with { | Some(true) | Some(false) -> 1 | None -> 0 }`,
+		},
+		{
+			name:    "unreachable arm",
+			input:   `match 1 with { | _ -> 1 | 0 -> 2 }`,
+			wantErr: "warning: test.ln:1:25: unreachable match arm",
+		},
+		{
+			name:    "float written two ways",
+			input:   `match 1.5 with { | 1.0 -> 1 | 1.00 -> 2 | _ -> 3 }`,
+			wantErr: "warning: test.ln:1:29: unreachable match arm",
+		},
+		{
+			name:    "int with leading zeros",
+			input:   `match 7 with { | 7 -> 1 | 007 -> 2 | _ -> 3 }`,
+			wantErr: "warning: test.ln:1:25: unreachable match arm",
+		},
+		{
+			name:    "escaped string",
+			input:   `match "A" with { | "A" -> 1 | "\x41" -> 2 | _ -> 3 }`,
+			wantErr: "warning: test.ln:1:29: unreachable match arm",
+		},
+		{
+			name:  "distinct chars",
+			input: `match 'a' with { | 'a' -> 1 | 'b' -> 2 | _ -> 3 }`,
+		},
+		{
+			name:  "string nil is not the empty string",
+			input: `match "nil" with { | "nil" -> 1 | nil -> 2 | _ -> 3 }`,
+		},
+		{
+			name:    "guarded arms",
+			input:   `match true with { | true -> 1 | false when 1 == 1 -> 2 }`,
+			wantErr: "warning: test.ln:1:1: match may not be exhaustive: `false` is only covered by guarded arms",
+		},
+	}
+
+	runChecks(t, tests)
+}
+
+func TestRecords(t *testing.T) {
+	const user = "record User { name: string, age: int }\n"
+	tests := []checkCase{
+		{
+			name: "literal, access and update",
+			input: user + `let u = { name: "ann", age: 30 }
+let v = { u with age: 31 }
+let n: string = v.name`,
+		},
+		{
+			name:    "missing field",
+			input:   user + `let u = { name: "ann" }`,
+			wantErr: "test.ln:2:9: record User is missing field(s) age",
+		},
+		{
+			name:    "extra field",
+			input:   user + `let u = { name: "ann", age: 1, email: "a" }`,
+			wantErr: "test.ln:2:32: record User has no field email",
+		},
+		{
+			name:    "field type",
+			input:   user + `let u = { name: 1, age: 1 }`,
+			wantErr: "field name of record User has type string, found int",
+		},
+		{
+			name: "unknown field access",
+			input: user + `let u = { name: "ann", age: 30 }
+let e = u.email`,
+			wantErr: "record User has no field email",
+		},
+		{
+			name: "record patterns",
+			input: user + `let n: string = match { name: "ann", age: 30 } with {
+	| { age: 0 } -> "newborn"
+	| { name } -> name
+}`,
+		},
+		{
+			name:    "non-exhaustive record pattern",
+			input:   user + `match { name: "ann", age: 30 } with { | { age: 0 } -> 1 }`,
+			wantErr: "non-exhaustive match: `{ name: _, age: _ }` not covered",
+		},
+		{
+			name: "generic record",
+			input: `record Box[T] { item: T }
+let b = { item: [1] }
+let i: [int] = b.item`,
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestTypeDeclarations(t *testing.T) {
+	tests := []checkCase{
+		{
+			name: "alias expands",
+			input: `type Point = (float, float)
+let p: Point = (1.0, 2.0)
+let x: float = match p with { | (a, _) -> a }`,
+		},
+		{
+			name: "generic alias",
+			input: `type Pair[T] = (T, T)
+let p: Pair[int] = (1, 2)`,
+		},
+		{
+			name: "alias named in mismatch",
+			input: `type Point = (float, float)
+let p: Point = (1, 2)`,
+			wantErr: "expected Point, found (int, int)",
+		},
+		{
+			name: "alias arity",
+			input: `type Pair[T] = (T, T)
+let p: Pair = (1, 2)`,
+			wantErr: "type Pair expects 1 type argument(s)",
+		},
+		{
+			name:    "alias cannot refer to itself",
+			input:   `type Names = [Names]`,
+			wantErr: "unknown type Names",
+		},
+		{
+			name: "newtype wraps and unwraps",
+			input: `newtype UserId = int
+let id: UserId = UserId(7)
+let n: int = match id with { | UserId(n) -> n }`,
+		},
+		{
+			name: "newtype is distinct",
+			input: `newtype UserId = int
+let n: int = UserId(7)`,
+			wantErr: "type mismatch: int vs UserId",
+		},
+		{
+			name:    "newtype undeclared variable",
+			input:   `newtype Box = [T]`,
+			wantErr: "newtype Box uses a type variable it does not declare",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestPipelines(t *testing.T) {
+	const defs = `let inc: fn(int) -> int = fn(x) { x }
+let apply: fn(fn(int) -> int, int) -> int = fn(f, x) { f(x) }
+let show: fn(int) -> string = fn(x) { "n" }
+`
+	tests := []checkCase{
+		{
+			name:  "pipe into function",
+			input: defs + `let a: int = 1 |> inc |> inc`,
+		},
+		{
+			name:  "pipe appends last argument",
+			input: defs + `let b: int = 1 |> apply(inc)`,
+		},
+		{
+			name: "composition",
+			input: defs + `let c: fn(int) -> string = inc >> show
+let d: string = 1 |> inc >> show`,
+		},
+		{
+			name:    "pipe type mismatch",
+			input:   defs + `let e = "x" |> inc`,
+			wantErr: "test.ln:4:13: |> cannot pass string to a function of type fn(int) -> int",
+		},
+		{
+			name:    "pipe into non-function",
+			input:   defs + `let f = 1 |> 2`,
+			wantErr: "right-hand side of |> must be a function, found int",
+		},
+		{
+			name:    "pipe arity",
+			input:   defs + `let g = 1 |> apply`,
+			wantErr: "|> calls a function of type fn(fn(int) -> int, int) -> int with 1 argument(s)",
+		},
+		{
+			name:    "bad composition",
+			input:   defs + `let h = show >> inc`,
+			wantErr: ">> cannot compose fn(int) -> string with fn(int) -> int",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestOperators(t *testing.T) {
+	tests := []checkCase{
+		{
+			name: "prefix and logical operators",
+			input: `let x = 5
+let neg: int = -x
+let f: float = -1.5
+let b: bool = !true && not false || true
+let m: int = 7 % 3`,
+		},
+		{
+			name:    "negate a string",
+			input:   `let s = -"a"`,
+			wantErr: "test.ln:1:9: no instance Num string",
+		},
+		{
+			name:    "not on int",
+			input:   `let n = not 1`,
+			wantErr: "operator not expects bool, found int",
+		},
+		{
+			name:    "and on int",
+			input:   `let n = true && 1`,
+			wantErr: "test.ln:1:14: operator && expects bool, found int",
+		},
+		{
+			name:    "modulo on float",
+			input:   `let n = 1.5 % 2`,
+			wantErr: "operator % expects int, found float",
+		},
+		{
+			name:  "negative literal patterns",
+			input: `let n: int = match -1 with { | -1 -> 0 | _ -> 1 }`,
+		},
+		{
+			name:    "colon is not an operator",
+			input:   `let n: int = 1 : 2`,
+			wantErr: "test.ln:1:16: : is not a binary operator",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestTypeClasses(t *testing.T) {
+	tests := []checkCase{
+		{
+			name: "builtin instances",
+			input: `let a: int = 1 + 2
+let b: float = 1.5 * 2.0
+let c: string = "a" + "b"
+let d: [int] = [1] + [2]
+let e: bool = "a" < "b"
+let f: bool = [1, 2] == [1, 2]
+let g: bool = (1, "a") != (2, "b")`,
+		},
+		{
+			name:    "add booleans",
+			input:   `let n = true + false`,
+			wantErr: "test.ln:1:14: no instance Num bool",
+		},
+		{
+			name: "compare functions",
+			input: `let f: fn(int) -> int = fn(x) { x }
+let b = f == f`,
+			wantErr: "no instance Eq fn(int) -> int",
+		},
+		{
+			name:    "order lists",
+			input:   `let b = [1] < [2]`,
+			wantErr: "no instance Ord list(int)",
+		},
+		{
+			name: "equality looks inside lists",
+			input: `let f: fn(int) -> int = fn(x) { x }
+let b = [f] == [f]`,
+			wantErr: "no instance Eq fn(int) -> int",
+		},
+		{
+			name: "equality looks inside classes",
+			input: `class L[T] { Nil Cons(T, L[T]) }
+class Box { Wrap(fn(int) -> int) }
+let a: bool = L.Cons(1, L.Nil) == L.Nil
+let f: fn(int) -> int = fn(x) { x }
+let b = Box.Wrap(f) == Box.Wrap(f)`,
+			wantErr: "no instance Eq Box: no instance Eq fn(int) -> int",
+		},
+		{
+			name: "class arguments are checked through fields",
+			input: `class L[T] { Nil Cons(T, L[T]) }
+let f: fn(int) -> int = fn(x) { x }
+let b = L.Cons(f, L.Nil) == L.Nil`,
+			wantErr: "no instance Eq fn(int) -> int",
+		},
+		{
+			name: "thunks have no equality",
+			input: `let a = lazy 1
+let b = a == a`,
+			wantErr: "no instance Eq Lazy[int]",
+		},
+		{
+			name:    "cells cannot be shown",
+			input:   `let u: unit = builtin_print(builtin_ref(1))`,
+			wantErr: "no instance Show Ref[int]",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestInstances(t *testing.T) {
+	src := `let n = 1 + 2
+let add = fn(a, b) { a + b }
+let x = add(1.5, 2.0)
+let r = builtin_ref([])
+let same = builtin_get(r) == builtin_get(r)
+r := [1]`
+	lx, tokens, err := lexer.Tokenize(src, "test.ln")
+	if err != nil {
+		t.Fatalf("unexpected lexing error: %v", err)
+	}
+	program, errs := parser.ParseProgram(tokens, lx)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	result := typechecker.Check(program, typechecker.Config{})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	want := map[string]string{
+		"1:11": "Num int",      // 1 + 2
+		"2:24": "Num T",        // a + b, left to add's callers
+		"3:9":  "Num float",    // add(1.5, 2.0)
+		"5:27": "Eq list(int)", // fixed by the assignment after it
+	}
+	got := map[string]string{}
+	for at, cs := range result.Instances {
+		for _, c := range cs {
+			s := c.String()
+			if _, isVar := c.Type.(*typechecker.TypeVar); isVar {
+				s = c.Class + " T"
+			}
+			got[fmt.Sprintf("%d:%d", at.Line, at.Column)] = s
+		}
+	}
+	for pos, w := range want {
+		if got[pos] != w {
+			t.Errorf("%s: expected instance %s, got %q", pos, w, got[pos])
+		}
+	}
+}
+
+func TestInference(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+	}{
+		{
+			name:     "identity",
+			input:    `let id = fn(x) { x }`,
+			expected: map[string]string{"id": "fn(T0) -> T0"},
+		},
+		{
+			name: "let polymorphism",
+			input: `let id = fn(x) { x }
+let a = id(1)
+let b = id("s")`,
+			expected: map[string]string{"a": "int", "b": "string"},
+		},
+		{
+			name:     "constraints propagate through the body",
+			input:    `let add = fn(a, b) { a + b }`,
+			expected: map[string]string{"add": "Num T0 => fn(T0, T0) -> T0"},
+		},
+		{
+			name:     "higher-order functions",
+			input:    `let twice = fn(f, x) { f(f(x)) }`,
+			expected: map[string]string{"twice": "fn(fn(T0) -> T0, T0) -> T0"},
+		},
+		{
+			name: "let rec",
+			input: `let rec len = fn(xs) {
+    match xs with { | [] -> 0 | [_, ...rest] -> 1 + len(rest) }
+}`,
+			expected: map[string]string{"len": "fn(list(T0)) -> int"},
+		},
+		{
+			name: "mutual recursion",
+			input: `let rec is_even = fn(n) { if n == 0 then true else is_odd(n - 1) }
+and is_odd = fn(n) { if n == 0 then false else is_even(n - 1) }`,
+			expected: map[string]string{"is_even": "fn(int) -> bool", "is_odd": "fn(int) -> bool"},
+		},
+		{
+			name: "recursive groups generalize together",
+			input: `let rec f = fn(x) { g(x) } and g = fn(y) { f(y) }
+let a: int = f(1)
+let b: string = g("s")`,
+			expected: map[string]string{"f": "fn(T0) -> T1", "g": "fn(T0) -> T1"},
+		},
+		{
+			name: "index, slice and blocks",
+			input: `let first = fn(xs) { xs[0] }
+let rest = fn(xs) { xs[1:] }
+let f = fn(x) {
+    let y = x + 1
+    y * 2
+}`,
+			expected: map[string]string{
+				"first": "fn(list(T0)) -> T0",
+				"rest":  "fn(list(T0)) -> list(T0)",
+				"f":     "fn(int) -> int",
+			},
+		},
+		{
+			name: "forward references at the top level",
+			input: `let a: fn(int) -> int = fn(x) { b(x) }
+let b: fn(int) -> int = fn(x) { x }`,
+			expected: map[string]string{"a": "fn(int) -> int"},
+		},
+		{
+			name:     "signature variables are shared",
+			input:    `let id: fn(T) -> T = fn(x) { x }`,
+			expected: map[string]string{"id": "fn(T0) -> T0"},
+		},
+		{
+			name: "signature variables scope over the body",
+			input: `let pair: fn(T) -> (T, T) = fn(x) {
+    let dup: fn(T) -> (T, T) = fn(y) { (y, x) }
+    dup(x)
+}`,
+			expected: map[string]string{"pair": "fn(T0) -> (T0, T0)"},
+		},
+		{
+			name:     "explicit forall",
+			input:    `let k: forall a b. fn(a, b) -> a = fn(x, y) { x }`,
+			expected: map[string]string{"k": "fn(T0, T1) -> T0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lx, tokens, err := lexer.Tokenize(tt.input, "test.ln")
+			if err != nil {
+				t.Fatalf("unexpected lexing error: %v", err)
+			}
+			program, errs := parser.ParseProgram(tokens, lx)
+			if len(errs) > 0 {
+				t.Fatalf("unexpected parse errors: %v", errs)
+			}
+			result := typechecker.Check(program, typechecker.Config{})
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			for name, want := range tt.expected {
+				s, ok := result.Env[name]
+				if !ok {
+					t.Fatalf("%s is not bound", name)
+				}
+				if got := s.String(); got != want {
+					t.Errorf("%s: expected %s, got %s", name, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInferenceErrors(t *testing.T) {
+	tests := []checkCase{
+		{
+			name: "constraint at the call site",
+			input: `let add = fn(a, b) { a + b }
+let n = add(true, false)`,
+			wantErr: "test.ln:2:9: no instance Num bool",
+		},
+		{
+			name:    "occurs check",
+			input:   `let f = fn(x) { x(x) }`,
+			wantErr: "test.ln:1:17: infinite type: T0 occurs in fn(T0) -> T1",
+		},
+		{
+			name: "variables are numbered within each error",
+			input: `let m = fn(a, b) { [a, b] }
+let bad: int = m`,
+			wantErr: "test.ln:2:16: type mismatch: int vs fn(T0, T0) -> list(T0)",
+		},
+		{
+			name: "argument type",
+			input: `let f = fn(x: int) { x }
+let y = f("a")`,
+			wantErr: "test.ln:2:11: argument 1 has type string, expected int",
+		},
+		{
+			name: "argument count",
+			input: `let f = fn(x: int) { x }
+let y = f(1, 2)`,
+			wantErr: "function of type fn(int) -> int expects 1 argument(s), got 2",
+		},
+		{
+			name:    "index must be int",
+			input:   `let y = [1, 2]["a"]`,
+			wantErr: "index must be int, found string",
+		},
+		{
+			name:    "signature variable forced to a type",
+			input:   `let f: fn(T) -> T = fn(x) { x + 1 }`,
+			wantErr: "test.ln:1:5: type variable T in the signature of f cannot be int",
+		},
+		{
+			name:    "signature variables forced together",
+			input:   `let f: fn(T, U) -> T = fn(x, y) { if true then x else y }`,
+			wantErr: "type variables T and U in the signature of f must be different",
+		},
+		{
+			name:    "unknown type name",
+			input:   `let f: fn(Strin) -> int = fn(x) { 1 }`,
+			wantErr: "test.ln:1:11: unknown type Strin",
+		},
+		{
+			name:    "variable not bound by forall",
+			input:   `let f: forall a. fn(a) -> b = fn(x) { x }`,
+			wantErr: "type variable b is not bound by forall",
+		},
+		{
+			name:    "lambda parameters are monomorphic",
+			input:   `let f = fn(g) { (g(1), g("a")) }`,
+			wantErr: "argument 1 has type string, expected int",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestRecursiveGroups(t *testing.T) {
+	tests := []checkCase{
+		{
+			name:    "names are not visible without rec",
+			input:   `let f = fn(x) { f(x) }`,
+			wantErr: "undefined identifier f",
+		},
+		{
+			name: "group members are checked against each other",
+			input: `let rec f = fn(x) { g(x) + 1 }
+and g = fn(y) { "s" }`,
+			wantErr: "type mismatch",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestLetPatterns(t *testing.T) {
+	const opt = "class Opt[T] { Some(T) None }\n"
+	tests := []checkCase{
+		{
+			name: "tuple and list patterns",
+			input: `let (x, y) = (1, "a")
+let [a, b] = [1, 2] else { builtin_panic("need two") }
+let s: string = y
+let n: int = a + b`,
+		},
+		{
+			name: "constructor pattern with else",
+			input: opt + `let Some(v) = Opt.Some(3) else { builtin_panic("none") }
+let n: int = v`,
+		},
+		{
+			name:    "refutable pattern needs else",
+			input:   opt + `let Opt.Some(v) = Opt.Some(3)`,
+			wantErr: "refutable pattern in let: `None` not covered",
+		},
+		{
+			name:    "else of an irrefutable pattern",
+			input:   `let (a, b) = (1, 2) else { builtin_panic("never") }`,
+			wantErr: "warning: test.ln:1:28: else block of let is unreachable",
+		},
+		{
+			name:    "else must not finish with a value",
+			input:   opt + `let Some(v) = Opt.Some(3) else { 0 }`,
+			wantErr: "else block of let must not finish with a value, found int",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestHoles(t *testing.T) {
+	tests := []checkCase{
+		{
+			name:    "named hole",
+			input:   `let n: int = ?todo + 1`,
+			wantErr: "test.ln:1:14: hole `?todo` has type `int`",
+		},
+		{
+			name:    "underscore hole",
+			input:   `let f = fn(s: string) { [s, _] }`,
+			wantErr: "hole `_` has type `string`",
+		},
+		{
+			name:    "hole in a generic position",
+			input:   `let f = fn(x) { ?body }`,
+			wantErr: "hole `?body` has type `T0`",
+		},
+	}
+	runChecks(t, tests)
+
+	fits := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "bindings of the hole's type",
+			input: `let inc = fn(x: int) { x + 1 }
+let name = "lunno"
+let twice = fn(f: fn(int) -> int, x: int) { f(f(x)) }
+let n = twice(?f, 1)`,
+			want: "bindings that fit: inc",
+		},
+		{
+			name: "not the declaration the hole is in",
+			input: `let single: fn(int) -> [int] = fn(n) { [n] }
+let h: fn(int) -> [int] = ?hole`,
+			want: "bindings that fit: single",
+		},
+		{
+			name: "not the recursive binding the hole is in",
+			input: `let dec: fn(int) -> int = fn(n) { n - 1 }
+let rec count: fn(int) -> int {
+    fn(n) { if n > 0 then ?again(dec(n)) else 0 }
+}`,
+			want: "bindings that fit: dec",
+		},
+	}
+	for _, tt := range fits {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := checkSource(t, tt.input)
+			if len(errs) != 1 {
+				t.Fatalf("expected one error, got %v", errs)
+			}
+			var te *typechecker.TypeError
+			if !errors.As(errs[0], &te) || te.Code != typechecker.CodeHole {
+				t.Fatalf("expected a hole error, got %v", errs[0])
+			}
+			if len(te.Notes) != 1 || te.Notes[0] != tt.want {
+				t.Errorf("notes: expected %q, got %q", tt.want, te.Notes)
+			}
+		})
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	const result = "class Result[T, E] { Ok(T) Err(E) }\n"
+	tests := []checkCase{
+		{
+			name: "unwraps ok values",
+			input: result + `let parse: fn(string) -> Result[int, string] { fn(s) { Result.Ok(1) } }
+let sum: fn(string, string) -> Result[int, string] {
+    fn(a, b) { Result.Ok(parse(a)? + parse(b)?) }
+}
+let twice = fn(s) { let n = parse(s)?
+    Result.Ok(n * 2) }
+let r: Result[int, string] = twice("1")`,
+		},
+		{
+			name: "error types must agree",
+			input: result + `let parse: fn(string) -> Result[int, string] { fn(s) { Result.Ok(1) } }
+let f: fn(string) -> Result[int, int] { fn(s) { Result.Ok(parse(s)?) } }`,
+			wantErr: "`?` returns Result[int, string] early, but the enclosing function returns Result[int, int]",
+		},
+		{
+			name:    "function must return a result",
+			input:   result + `let f: fn(Result[int, string]) -> int { fn(r) { r? + 1 } }`,
+			wantErr: "early, but the enclosing function returns int",
+		},
+		{
+			name:    "operand must be a result",
+			input:   result + `let f = fn(x: int) { Result.Ok(x?) }`,
+			wantErr: "the `?` operator needs a Result, found int",
+		},
+		{
+			name:    "outside a function",
+			input:   result + `let x = Result.Ok(1)?`,
+			wantErr: "the `?` operator can only be used inside a function",
+		},
+		{
+			name:    "without a result type",
+			input:   `let f = fn(x) { x? }`,
+			wantErr: "the `?` operator needs a Result type with Ok and Err",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestTry(t *testing.T) {
+	tests := []checkCase{
+		{
+			name: "recover with the panic",
+			input: `let div = fn(a: int, b: int) { try { a / b } recover e -> {
+    builtin_print(e.message)
+    0
+} }
+let at: string = try { builtin_panic("boom") } recover p -> p.file + ":" + p.message
+let line: int = try { 0 } recover p -> p.line`,
+		},
+		{
+			name:  "ignore the panic",
+			input: `let n: int = try { 1 } recover _ -> 0`,
+		},
+		{
+			name:    "handler type must match the body",
+			input:   `let n = try { 1 } recover e -> e.message`,
+			wantErr: "test.ln:1:32: type mismatch: int vs string",
+		},
+		{
+			name:    "panic fields",
+			input:   `let n = try { 1 } recover e -> e.code`,
+			wantErr: "record Panic has no field code",
+		},
+		{
+			name:    "binding is scoped to the handler",
+			input:   `let n = try { e } recover e -> 0`,
+			wantErr: "undefined",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestLazy(t *testing.T) {
+	const seq = "class Seq[T] { Empty Cons(T, Lazy[Seq[T]]) }\n"
+	tests := []checkCase{
+		{
+			name: "force a thunk",
+			input: `let later = lazy { 40 + 2 }
+let n: int = builtin_force(later)`,
+		},
+		{
+			name: "infinite sequence",
+			input: seq + `let rec nats: fn(int) -> Seq[int] { fn(n) { Seq.Cons(n, lazy nats(n + 1)) } }
+let second: int = match nats(0) with {
+    | Seq.Cons(_, rest) -> match builtin_force(rest) with {
+        | Seq.Cons(x, _) -> x
+        | Seq.Empty -> 0
+    }
+    | Seq.Empty -> 0
+}`,
+		},
+		{
+			name:    "lazy is not its value",
+			input:   `let n: int = lazy 1`,
+			wantErr: "type mismatch: int vs Lazy[int]",
+		},
+		{
+			name:    "tail must be lazy",
+			input:   seq + `let s: Seq[int] = Seq.Cons(1, Seq.Empty)`,
+			wantErr: "argument 2 has type Seq[T",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestValueRestriction(t *testing.T) {
+	const opt = "class Opt[T] { Some(T) None }\n"
+	tests := []checkCase{
+		{
+			name: "a cell has one type",
+			input: `let r = builtin_ref([])
+r := [1]
+let s: [string] = builtin_get(r)`,
+			wantErr: "type mismatch: string vs int",
+		},
+		{
+			name: "a local cell has one type",
+			input: `let f = fn() {
+    let r = builtin_ref([])
+    r := [true]
+    let s: [string] = builtin_get(r)
+    s
+}`,
+			wantErr: "type mismatch: string vs bool",
+		},
+		{
+			name: "a cell's constraints are solved once its type is fixed",
+			input: `let r = builtin_ref([])
+let s = builtin_get(r) == builtin_get(r)
+r := [fn(x) { x + 1 }]`,
+			wantErr: "test.ln:2:24: no instance Eq fn(int) -> int",
+		},
+		{
+			name: "a cell whose type is never fixed",
+			input: `let r = builtin_ref([])
+let s = builtin_get(r) == builtin_get(r)`,
+			wantErr: "test.ln:2:24: cannot choose an instance Eq T0: its type is never fixed",
+		},
+		{
+			name: "a capitalized function is not a constructor",
+			input: `let MkRef = builtin_ref
+let r = MkRef([])
+r := [1]
+let s: [string] = builtin_get(r)`,
+			wantErr: "type mismatch: string vs int",
+		},
+		{
+			name: "a let shadowing a constructor is not one",
+			input: opt + `let Some = fn(x) { Opt.Some(builtin_ref(x)) }
+let r = Some([])
+let w: unit = match r with {
+    | Opt.Some(cell) -> cell := [1]
+    | Opt.None -> ()
+}
+let s: Opt[Ref[[string]]] = r`,
+			wantErr: "type mismatch: string vs int",
+		},
+		{
+			name: "values are still generalized",
+			input: opt + `let id = fn(x) { x }
+let a: int = id(1)
+let b: string = id("b")
+let empty = []
+let xs: [int] = empty
+let ys: [string] = empty
+let none = Opt.None
+let some = Opt.Some([])
+let n1: Opt[int] = none
+let n2: Opt[bool] = none
+let s1: Opt[[int]] = some
+let s2: Opt[[char]] = some`,
+		},
+		{
+			name: "counter",
+			input: `let counter = builtin_ref(0)
+let bump = fn() { counter := builtin_get(counter) + 1 }
+let done: unit = bump()
+let n: int = builtin_get(counter)`,
+		},
+		{
+			name:    "signature cannot be polymorphic",
+			input:   `let r: Ref[[T]] = builtin_ref([])`,
+			wantErr: "the signature of r cannot use type variable T",
+		},
+		{
+			name:    "assign to a non-reference",
+			input:   `let f = fn(x: int) { x := 2 }`,
+			wantErr: "operator := expects Ref[int], found int",
+		},
+	}
+	runChecks(t, tests)
+}
+
+func TestResult(t *testing.T) {
+	src := `let f = fn(x) { x + 1 }
+let s = f(2)`
+	lx, tokens, err := lexer.Tokenize(src, "test.ln")
+	if err != nil {
+		t.Fatalf("unexpected lexing error: %v", err)
+	}
+	program, errs := parser.ParseProgram(tokens, lx)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	result := typechecker.Check(program, typechecker.Config{})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	// x is bound before `x + 1` solves its type, so this checks that the
+	// result carries the final substitution.
+	for name, typ := range result.Names {
+		if name.Lexeme == "x" && typ.String() != "int" {
+			t.Errorf("x: expected int, got %s", typ)
+		}
+	}
+	call := program.Expressions[1].(*parser.VariableDeclarationExpression).Value
+	if typ := result.Types[call]; typ == nil || typ.String() != "int" {
+		t.Errorf("f(2): expected int, got %v", typ)
+	}
+
+	// The right-hand side of a pipe is only checked as part of the pipe,
+	// but it still gets a type.
+	src = `let g = fn(n: int, s: string) { s }
+let p = "a" |> g(1)`
+	lx, tokens, err = lexer.Tokenize(src, "test.ln")
+	if err != nil {
+		t.Fatalf("unexpected lexing error: %v", err)
+	}
+	program, errs = parser.ParseProgram(tokens, lx)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	result = typechecker.Check(program, typechecker.Config{})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	pipe := program.Expressions[1].(*parser.VariableDeclarationExpression).Value.(*parser.InfixExpression)
+	if typ := result.Types[pipe.Right]; typ == nil || typ.String() != "string" {
+		t.Errorf("g(1) in a pipe: expected string, got %v", typ)
+	}
+}
+
+func TestDumpTypes(t *testing.T) {
+	src := `let id = fn(x) { x }
+let rec len = fn(xs) { match xs with { | [] -> 0 | [_, ...rest] -> 1 + len(rest) } }
+and count = fn(ys) { len(ys) }`
+	lx, tokens, err := lexer.Tokenize(src, "test.ln")
+	if err != nil {
+		t.Fatalf("unexpected lexing error: %v", err)
+	}
+	program, errs := parser.ParseProgram(tokens, lx)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	result := typechecker.Check(program, typechecker.Config{})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	// Members of a recursive group are top-level declarations too, so they
+	// show their schemes rather than their monotypes.
+	dump := typechecker.DumpTypes(program, result)
+	for _, want := range []string{
+		"1:5 id: fn(T0) -> T0\n",
+		"2:9 len: fn(list(T0)) -> int\n",
+		"3:5 count: fn(list(T0)) -> int\n",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("expected %q in\n%s", want, dump)
+		}
+	}
+}
+
+func TestTypeErrorDetails(t *testing.T) {
+	src := `let x: string = 3`
+	errs := checkSource(t, src)
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", errs)
+	}
+	var te *typechecker.TypeError
+	if !errors.As(errs[0], &te) {
+		t.Fatalf("expected a *TypeError, got %T", errs[0])
+	}
+	if te.Code != typechecker.CodeMismatch {
+		t.Errorf("code: expected %s, got %s", typechecker.CodeMismatch, te.Code)
+	}
+	if te.Expected.String() != "string" || te.Found.String() != "int" {
+		t.Errorf("expected string and int, got %s and %s", te.Expected, te.Found)
+	}
+	if te.Span.Line != 1 || te.Span.Column != 17 {
+		t.Errorf("span: expected 1:17, got %d:%d", te.Span.Line, te.Span.Column)
+	}
+	out := te.Render(func(string) []rune { return []rune(src) })
+	for _, want := range []string{
+		"error[E0001]: type mismatch: string vs int",
+		"^ expected string, found int",
+		"^ expected because of this annotation",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("render: expected %q in\n%s", want, out)
+		}
+	}
+
+	errs = checkSource(t, `let f = fn(x) { x(x) }`)
+	if len(errs) != 1 || !errors.As(errs[0], &te) {
+		t.Fatalf("expected one type error, got %v", errs)
+	}
+	if te.Expected.String() != "T0" || te.Found.String() != "fn(T0) -> T1" {
+		t.Errorf("expected T0 and fn(T0) -> T1, got %s and %s", te.Expected, te.Found)
+	}
+}
+
+// cacheEvents lists what the cache did, one "hit module" or
+// "miss module: reason" per load, and clears the log for the next check.
+func cacheEvents(cache *typechecker.Cache) []string {
+	var out []string
+	for _, e := range cache.Events {
+		if e.Hit {
+			out = append(out, "hit "+e.Module)
+		} else {
+			out = append(out, "miss "+e.Module+": "+e.Reason)
+		}
+	}
+	cache.Events = nil
+	return out
+}
+
+func TestInterfaceCache(t *testing.T) {
+	const (
+		program = "import a\nlet n: int = twice(1)\n"
+		moduleA = "import b\npub let twice = fn(x: int) { double(x) }\n"
+		moduleB = "pub let double = fn(x: int) { x * 2 }\n"
+	)
+	tests := []struct {
+		name string
+		// change edits the modules or the cache between the two checks.
+		change func(t *testing.T, modules, cache string, stored map[string]string)
+		want   []string
+	}{
+		{
+			name: "unchanged modules hit",
+			want: []string{"hit b", "hit a"},
+		},
+		{
+			name: "changed source misses",
+			change: func(t *testing.T, modules, cache string, stored map[string]string) {
+				rewrite(t, filepath.Join(modules, "a.ln"), moduleA+"let unused = 0\n")
+			},
+			want: []string{"miss a: no cached interface", "hit b"},
+		},
+		{
+			name: "changed dependency invalidates importer",
+			change: func(t *testing.T, modules, cache string, stored map[string]string) {
+				rewrite(t, filepath.Join(modules, "b.ln"), "pub let double = fn(x: int) { x + x }\n")
+			},
+			want: []string{"miss b: no cached interface", "miss a: dependency b changed"},
+		},
+		{
+			name: "stale interface version misses",
+			change: func(t *testing.T, modules, cache string, stored map[string]string) {
+				hash := stored["a"]
+				path := filepath.Join(cache, hash[:2], hash+".lni")
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var iface map[string]any
+				if err := json.Unmarshal(data, &iface); err != nil {
+					t.Fatal(err)
+				}
+				iface["version"] = 0
+				data, err = json.Marshal(iface)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rewrite(t, path, string(data))
+			},
+			want: []string{"miss a: interface version 0 is stale", "hit b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := writeModules(t, map[string]string{"a": moduleA, "b": moduleB})
+			config.Cache = typechecker.NewCache(t.TempDir())
+			expectError(t, checkWith(t, program, config), "")
+			stored := map[string]string{}
+			for _, e := range config.Cache.Events {
+				stored[e.Module] = e.Hash
+			}
+			if got := cacheEvents(config.Cache); fmt.Sprint(got) != fmt.Sprint([]string{"miss a: no cached interface", "miss b: no cached interface"}) {
+				t.Fatalf("first check: expected two misses, got %v", got)
+			}
+			if tt.change != nil {
+				tt.change(t, config.SearchPaths[0], config.Cache.Dir, stored)
+			}
+			expectError(t, checkWith(t, program, config), "")
+			if got := cacheEvents(config.Cache); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestInterfaceRoundTrip checks a module once from source and once from
+// its cached interface, which between them hold every kind of type, and
+// expects the same schemes both times.
+func TestInterfaceRoundTrip(t *testing.T) {
+	config := writeModules(t, map[string]string{"kinds": `pub class Shape[T] { pub Circle(T) pub Empty }
+pub record Point { x: int, y: float }
+pub let i: int = 1
+pub let f: float = 1.5
+pub let b: bool = true
+pub let s: string = "s"
+pub let c: char = 'c'
+pub let u: unit = builtin_print(s)
+pub let l: [int] = [1]
+pub let p: (int, string) = (1, "a")
+pub let h: fn(int) -> bool = fn(n) { n > 0 }
+pub let n: Shape[int] = Shape.Circle(1)
+pub let pt: Point = { x: 1, y: 2.0 }
+pub let id = fn(x) { x }
+pub let add = fn(x, y) { x + y }
+`})
+	config.Cache = typechecker.NewCache(t.TempDir())
+	program := `import kinds
+let r: int = match n with { | Shape.Circle(k) -> k | Shape.Empty -> add(pt.x, i) }`
+	names := []string{"i", "f", "b", "s", "c", "u", "l", "p", "h", "n", "pt", "id", "add", "Circle", "Empty"}
+
+	schemes := func() map[string]string {
+		result := checkResult(t, program, config)
+		expectError(t, result.Errors, "")
+		out := map[string]string{}
+		for _, name := range names {
+			s, ok := result.Env[name]
+			if !ok {
+				t.Fatalf("%s is not bound", name)
+			}
+			out[name] = s.String()
+		}
+		return out
+	}
+	fresh := schemes()
+	if got := cacheEvents(config.Cache); len(got) != 1 || got[0] != "miss kinds: no cached interface" {
+		t.Fatalf("first check: expected a miss, got %v", got)
+	}
+	cached := schemes()
+	if got := cacheEvents(config.Cache); len(got) != 1 || got[0] != "hit kinds" {
+		t.Fatalf("second check: expected a hit, got %v", got)
+	}
+	for _, name := range names {
+		if fresh[name] != cached[name] {
+			t.Errorf("%s: %s from source, %s from the interface", name, fresh[name], cached[name])
+		}
+	}
+}
+
+func rewrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,213 @@
+package typechecker
+
+import (
+	"lunno/internal/lexer"
+)
+
+// Constraint requires Type to have an instance of the type class Class.
+type Constraint struct {
+	Class string
+	Type  Type
+}
+
+func (c Constraint) String() string {
+	return c.Class + " " + c.Type.String()
+}
+
+type pendingConstraint struct {
+	Constraint
+	Position lexer.Token
+}
+
+// instances lists the builtin instance of each type class by type shape.
+// Lists and tuples are instances when their elements are, so `Eq [int]`
+// holds but `Eq [fn(int) -> int]` does not. A named type is an instance
+// when the fields of all its constructors are; see solveNamed.
+var instances = map[string]map[string]bool{
+	"Num":  {"int": true, "float": true},
+	"Eq":   {"int": true, "float": true, "bool": true, "string": true, "char": true, "unit": true, "list": true, "tuple": true, "named": true},
+	"Ord":  {"int": true, "float": true, "string": true, "char": true},
+	"Show": {"int": true, "float": true, "bool": true, "string": true, "char": true, "unit": true, "list": true, "tuple": true, "named": true},
+}
+
+func typeShape(t Type) (string, []Type) {
+	switch t := t.(type) {
+	case *IntType:
+		return "int", nil
+	case *FloatType:
+		return "float", nil
+	case *BoolType:
+		return "bool", nil
+	case *StringType:
+		return "string", nil
+	case *CharType:
+		return "char", nil
+	case *UnitType:
+		return "unit", nil
+	case *ListType:
+		return "list", []Type{t.Element}
+	case *TupleType:
+		return "tuple", t.Elements
+	case *NamedType:
+		return "named", t.Arguments
+	}
+	return "fn", nil
+}
+
+// solve reduces a constraint to constraints on type variables, or fails
+// when some type involved has no instance.
+func (checker *Checker) solve(c Constraint) ([]Constraint, error) {
+	return checker.solveIn(c, map[string]bool{})
+}
+
+// solveIn solves c while the named types in open are being solved. A
+// recursive type meeting itself again adds nothing, so Eq List[int]
+// holds without looping on the list's tail.
+func (checker *Checker) solveIn(c Constraint, open map[string]bool) ([]Constraint, error) {
+	if _, ok := c.Type.(*TypeVar); ok {
+		return []Constraint{c}, nil
+	}
+	shape, args := typeShape(c.Type)
+	if !instances[c.Class][shape] {
+		return nil, failure("no instance %s %s", c.Class, c.Type)
+	}
+	if nt, ok := c.Type.(*NamedType); ok {
+		fields, err := checker.instanceFields(c.Class, nt)
+		if err != nil {
+			return nil, err
+		}
+		if open[nt.Name] {
+			return nil, nil
+		}
+		open[nt.Name] = true
+		defer delete(open, nt.Name)
+		args = fields
+	}
+	var out []Constraint
+	for _, arg := range args {
+		residual, err := checker.solveIn(Constraint{Class: c.Class, Type: arg}, open)
+		if err != nil {
+			if _, named := c.Type.(*NamedType); named {
+				err = failure("no instance %s %s: %v", c.Class, c.Type, err)
+			}
+			return nil, err
+		}
+		out = append(out, residual...)
+	}
+	return out, nil
+}
+
+// instanceFields returns the types a named type's instance depends on:
+// the fields of every constructor, with the type's arguments substituted.
+// A type without constructors, such as Lazy or Ref, is opaque and has no
+// instances. A class that is not in scope is judged by its arguments.
+func (checker *Checker) instanceFields(class string, t *NamedType) ([]Type, error) {
+	def, ok := checker.env.getClass(t.Name)
+	if !ok || len(def.TypeParams) != len(t.Arguments) {
+		return t.Arguments, nil
+	}
+	if len(def.Constructors) == 0 {
+		return nil, failure("no instance %s %s", class, t)
+	}
+	s := Subst{}
+	for i, id := range def.TypeParams {
+		s[id] = t.Arguments[i]
+	}
+	var fields []Type
+	for _, ctor := range def.Constructors {
+		for _, f := range ctor.Fields {
+			fields = append(fields, apply(f, s))
+		}
+	}
+	return fields, nil
+}
+
+// require asks for an instance of class for t at a use site, and records
+// it in the result for the backend to pick the implementation.
+func (checker *Checker) require(class string, t Type, at lexer.Token) {
+	checker.recordInstance(at, Constraint{Class: class, Type: t})
+	checker.constrain(class, t, at)
+}
+
+func (checker *Checker) constrain(class string, t Type, at lexer.Token) {
+	residual, err := checker.solve(Constraint{Class: class, Type: t})
+	if err != nil {
+		checker.errors = append(checker.errors, errorAt(CodeNoInstance, at, "%v", err))
+		return
+	}
+	for _, c := range residual {
+		checker.pending = append(checker.pending, pendingConstraint{Constraint: c, Position: at})
+	}
+}
+
+// solvePending re-checks deferred constraints once the substitution has
+// resolved some of their type variables.
+func (checker *Checker) solvePending() {
+	pending := checker.pending
+	checker.pending = nil
+	for _, p := range pending {
+		checker.constrain(p.Class, checker.apply(p.Type), p.Position)
+	}
+}
+
+// solveRemaining solves the deferred constraints a last time once the
+// whole module is checked, since a later expression may fix the type of a
+// monomorphic binding, as `r := [1]` does after `let r = builtin_ref([])`.
+// A constraint still on a variable then has no type to pick an instance
+// for.
+func (checker *Checker) solveRemaining() {
+	checker.solvePending()
+	for _, p := range checker.pending {
+		checker.errors = append(checker.errors, errorAt(CodeNoInstance, p.Position,
+			"cannot choose an instance %s: its type is never fixed", p.Constraint))
+	}
+	checker.pending = nil
+}
+
+// generalize quantifies t over the variables free in it but not in the
+// environment, and moves the deferred constraints on those variables
+// into the scheme. value says whether t is the type of a syntactic value.
+func (checker *Checker) generalize(t Type, value bool) *Scheme {
+	checker.solvePending()
+	s := generalize(checker.env, checker.apply(t), checker.subst, value)
+	var rest []pendingConstraint
+	for _, p := range checker.pending {
+		tv, ok := p.Type.(*TypeVar)
+		if !ok || !contains(s.TypeVars, tv.ID) {
+			rest = append(rest, p)
+			continue
+		}
+		if !s.hasConstraint(p.Constraint) {
+			s.Constraints = append(s.Constraints, p.Constraint)
+		}
+	}
+	checker.pending = rest
+	return s
+}
+
+// instantiateAt instantiates s and requires its constraints at the use
+// site, so `add(true, false)` reports a missing Num bool instance there.
+func (checker *Checker) instantiateAt(s *Scheme, at lexer.Token) Type {
+	fresh := Subst{}
+	for _, id := range s.TypeVars {
+		fresh[id] = checker.freshVar()
+	}
+	for _, c := range s.Constraints {
+		checker.require(c.Class, apply(c.Type, fresh), at)
+	}
+	return apply(s.Type, fresh)
+}
+
+func (s *Scheme) hasConstraint(c Constraint) bool {
+	for _, existing := range s.Constraints {
+		if existing.Class != c.Class {
+			continue
+		}
+		a, aok := existing.Type.(*TypeVar)
+		b, bok := c.Type.(*TypeVar)
+		if aok && bok && a.ID == b.ID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package typechecker
+
+import (
+	"lunno/internal/lexer"
+	"lunno/internal/parser"
+)
+
+// aliasType names a type alias in error messages. Only the alias's
+// expansion takes part in unification.
+type aliasType struct {
+	name string
+	args []Type
+}
+
+func (*aliasType) isType() {}
+
+func (t *aliasType) String() string {
+	return (&NamedType{Name: t.name, Arguments: t.args}).String()
+}
+
+func (checker *Checker) applyAlias(t *aliasType) *aliasType {
+	args := make([]Type, len(t.args))
+	for i, a := range t.args {
+		args[i] = checker.apply(a)
+	}
+	return &aliasType{name: t.name, args: args}
+}
+
+// declareAlias registers a type alias as a class without constructors
+// whose Alias is expanded by resolveType wherever the alias is named.
+func (checker *Checker) declareAlias(e *parser.TypeAliasDeclarationExpression) {
+	name := e.Name.Lexeme
+	if _, exists := checker.env.classes[name]; exists {
+		checker.errors = append(checker.errors, errorAt(CodeDuplicate, e.Name, "type %s is already declared", name))
+		return
+	}
+	class := &ClassDef{
+		Name:   name,
+		Module: checker.module,
+		Public: e.Public,
+	}
+	params := checker.bindTypeParameters(class, e.TypeParameters)
+
+	old, oldMode := checker.typeParams, checker.typeVarMode
+	checker.typeParams, checker.typeVarMode = params, classTypeVars
+	defer func() { checker.typeParams, checker.typeVarMode = old, oldMode }()
+	// The alias is not in scope in its own definition, so it cannot
+	// expand to a type containing itself.
+	class.Alias = checker.resolveType(e.Type)
+	checker.checkDeclaredVars(class, class.Alias, e.Name, "type alias")
+	checker.env.setClass(name, class)
+}
+
+// declareNewtype registers a newtype as a class with one constructor,
+// named after the type, that wraps a value of the underlying type.
+func (checker *Checker) declareNewtype(e *parser.NewtypeDeclarationExpression) {
+	name := e.Name.Lexeme
+	if _, exists := checker.env.classes[name]; exists {
+		checker.errors = append(checker.errors, errorAt(CodeDuplicate, e.Name, "type %s is already declared", name))
+		return
+	}
+	class := &ClassDef{
+		Name:    name,
+		Module:  checker.module,
+		Public:  e.Public,
+		Newtype: true,
+	}
+	params := checker.bindTypeParameters(class, e.TypeParameters)
+	checker.env.setClass(name, class)
+
+	old, oldMode := checker.typeParams, checker.typeVarMode
+	checker.typeParams, checker.typeVarMode = params, classTypeVars
+	defer func() { checker.typeParams, checker.typeVarMode = old, oldMode }()
+	wrapped := checker.resolveType(e.Type)
+	checker.checkDeclaredVars(class, wrapped, e.Name, "newtype")
+	ctor := &ConstructorDef{
+		Name:   name,
+		Class:  name,
+		Fields: []Type{wrapped},
+		Public: e.Public,
+	}
+	class.Constructors = []*ConstructorDef{ctor}
+	checker.env.declare(e.Name)
+	checker.setConstructor(name, ctor.scheme(class))
+}
+
+func (checker *Checker) checkDeclaredVars(class *ClassDef, t Type, at lexer.Token, kind string) {
+	for _, id := range freeTypeVars(t) {
+		if !contains(class.TypeParams, id) {
+			checker.errors = append(checker.errors,
+				errorAt(CodeInvalid, at, "%s %s uses a type variable it does not declare", kind, class.Name))
+			return
+		}
+	}
+}
@@ -2,7 +2,10 @@ package typechecker
 
 import (
 	"fmt"
+	"lunno/internal/lexer"
 	"lunno/internal/parser"
+	"sort"
+	"unicode"
 )
 
 type Type interface {
@@ -22,11 +25,20 @@ type (
 		Element Type
 	}
 
+	TupleType struct {
+		Elements []Type
+	}
+
 	FunctionType struct {
 		Parameters []Type
 		Return     Type
 	}
 
+	NamedType struct {
+		Name      string
+		Arguments []Type
+	}
+
 	TypeVar struct {
 		ID int
 	}
@@ -39,7 +51,9 @@ func (*StringType) isType()   {}
 func (*CharType) isType()     {}
 func (*UnitType) isType()     {}
 func (*ListType) isType()     {}
+func (*TupleType) isType()    {}
 func (*FunctionType) isType() {}
+func (*NamedType) isType()    {}
 func (*TypeVar) isType()      {}
 
 func (*IntType) String() string {
@@ -70,6 +84,17 @@ func (t *ListType) String() string {
 	return "list(" + t.Element.String() + ")"
 }
 
+func (t *TupleType) String() string {
+	s := "("
+	for i, e := range t.Elements {
+		if i > 0 {
+			s += ", "
+		}
+		s += e.String()
+	}
+	return s + ")"
+}
+
 func (t *FunctionType) String() string {
 	s := "fn("
 	for i, p := range t.Parameters {
@@ -82,6 +107,20 @@ func (t *FunctionType) String() string {
 	return s
 }
 
+func (t *NamedType) String() string {
+	if len(t.Arguments) == 0 {
+		return t.Name
+	}
+	s := t.Name + "["
+	for i, a := range t.Arguments {
+		if i > 0 {
+			s += ", "
+		}
+		s += a.String()
+	}
+	return s + "]"
+}
+
 func (t *TypeVar) String() string {
 	return fmt.Sprintf("T%d", t.ID)
 }
@@ -102,13 +141,69 @@ func (checker *Checker) resolveType(typ parser.TypeNode) Type {
 			return &CharType{}
 		case "unit":
 			return &UnitType{}
-		default:
+		}
+		if tv, ok := checker.typeParams[t.Name]; ok {
+			return tv
+		}
+		if class, ok := checker.env.getClass(t.Name); ok {
+			if len(class.TypeParams) != 0 {
+				checker.errors = append(checker.errors,
+					errorAt(CodeArity, t.Pos, "type %s expects %d type argument(s)", t.Name, len(class.TypeParams)))
+			}
+			if class.Alias != nil {
+				checker.aliases[t] = &aliasType{name: class.Name}
+			}
+			return class.instantiate(checker)
+		}
+		return checker.typeVariable(t)
+	case *parser.ForallType:
+		if checker.typeParams == nil {
+			checker.typeParams = map[string]Type{}
+			defer func() { checker.typeParams = nil }()
+		}
+		for _, v := range t.Variables {
+			checker.typeParams[v.Lexeme] = checker.freshVar()
+		}
+		old := checker.typeVarMode
+		checker.typeVarMode = forallTypeVars
+		defer func() { checker.typeVarMode = old }()
+		return checker.resolveType(t.Type)
+	case *parser.GenericType:
+		class, ok := checker.env.getClass(t.Name)
+		if !ok {
+			checker.errors = append(checker.errors, errorAt(CodeUndefined, t.Position, "unknown type %s", t.Name))
 			return checker.freshVar()
 		}
+		if len(class.TypeParams) != len(t.Arguments) {
+			checker.errors = append(checker.errors,
+				errorAt(CodeArity, t.Position, "type %s expects %d type argument(s), got %d",
+					t.Name, len(class.TypeParams), len(t.Arguments)))
+			return class.instantiate(checker)
+		}
+		args := make([]Type, len(t.Arguments))
+		for i, a := range t.Arguments {
+			args[i] = checker.resolveType(a)
+		}
+		if class.Alias != nil {
+			checker.aliases[t] = &aliasType{name: class.Name, args: args}
+			return class.expand(args)
+		}
+		return &NamedType{
+			Name:      class.Name,
+			Arguments: args,
+		}
 	case *parser.ListType:
 		return &ListType{
 			Element: checker.resolveType(t.Element),
 		}
+	case *parser.TupleType:
+		elems := make([]Type, len(t.Elements))
+		for i, e := range t.Elements {
+			elems[i] = checker.resolveType(e)
+		}
+		return &TupleType{
+			Elements: elems,
+		}
 	case *parser.FunctionType:
 		params := make([]Type, len(t.Parameters))
 		for i, p := range t.Parameters {
@@ -125,3 +220,116 @@ func (checker *Checker) resolveType(typ parser.TypeNode) Type {
 	}
 	return nil
 }
+
+// typeVarMode says how resolveType treats a type variable name that is
+// not bound in typeParams.
+type typeVarMode int
+
+const (
+	// bindTypeVars binds it in typeParams, so later uses share it.
+	bindTypeVars typeVarMode = iota
+	// forallTypeVars rejects it, since forall lists every variable.
+	forallTypeVars
+	// classTypeVars leaves it unbound for the class checks to report.
+	classTypeVars
+)
+
+// isTypeVariableName reports whether an unknown name in an annotation is
+// read as a type variable: a lowercase name, or a capital letter optionally
+// followed by digits, as in the stdlib's `fn([T]) -> [U]`.
+func isTypeVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	if unicode.IsLower(rune(name[0])) {
+		return true
+	}
+	if !unicode.IsUpper(rune(name[0])) {
+		return false
+	}
+	for _, r := range name[1:] {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (checker *Checker) typeVariable(t *parser.SimpleType) Type {
+	if !isTypeVariableName(t.Name) {
+		checker.errors = append(checker.errors, errorAt(CodeUndefined, t.Pos, "unknown type %s", t.Name))
+		return checker.freshVar()
+	}
+	tv := checker.freshVar()
+	switch checker.typeVarMode {
+	case forallTypeVars:
+		checker.errors = append(checker.errors, errorAt(CodeUndefined, t.Pos, "type variable %s is not bound by forall", t.Name))
+	case bindTypeVars:
+		if checker.typeParams != nil {
+			checker.typeParams[t.Name] = tv
+		}
+	}
+	return tv
+}
+
+// enterTypeScope starts the type variable scope of a declaration. Names
+// bound by an enclosing declaration keep their variables, so a nested
+// `loop: fn([T]) -> [T]` refers to its parent's T.
+func (checker *Checker) enterTypeScope() map[string]Type {
+	outer := checker.typeParams
+	scope := map[string]Type{}
+	for name, t := range outer {
+		scope[name] = t
+	}
+	checker.typeParams = scope
+	return outer
+}
+
+// requireMonomorphic rejects type variables introduced by the signature
+// of a declaration whose value is not generalized: they would claim the
+// value has every type when it has one type that is not known yet.
+func (checker *Checker) requireMonomorphic(outer map[string]Type, at lexer.Token, decl string) {
+	var names []string
+	for name, t := range checker.typeParams {
+		if outer[name] != t {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	checker.errors = append(checker.errors, errorAt(CodeSignature, at,
+		"the signature of %s cannot use type variable %s: its value is not a function or constructor, so it is not generalized",
+		decl, names[0]))
+}
+
+// leaveTypeScope ends the scope started by enterTypeScope. The variables
+// the declaration introduced are rigid: its value may not force one to a
+// specific type, or two of them to the same type.
+func (checker *Checker) leaveTypeScope(outer map[string]Type, at lexer.Token, decl string) {
+	var names []string
+	for name, t := range checker.typeParams {
+		if outer[name] != t {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	seen := map[int]string{}
+	for _, name := range names {
+		t := checker.apply(checker.typeParams[name])
+		tv, ok := t.(*TypeVar)
+		if !ok {
+			checker.errors = append(checker.errors, errorAt(CodeSignature, at,
+				"type variable %s in the signature of %s cannot be %s", name, decl, t))
+			continue
+		}
+		if other, dup := seen[tv.ID]; dup {
+			checker.errors = append(checker.errors, errorAt(CodeSignature, at,
+				"type variables %s and %s in the signature of %s must be different", other, name, decl))
+			continue
+		}
+		seen[tv.ID] = name
+	}
+	checker.typeParams = outer
+}
@@ -1,13 +1,41 @@
 package typechecker
 
-import "fmt"
+import (
+	"fmt"
+	"lunno/internal/parser"
+)
 
 type Subst map[int]Type
 
 type Checker struct {
-	env     *Env
-	nextVar int
-	errors  []error
+	env         *Env
+	nextVar     int
+	errors      []error
+	loader      *loader
+	module      string
+	private     map[string]string
+	imports     map[string]string
+	typeParams  map[string]Type
+	typeVarMode typeVarMode
+	result      *Result
+	records     map[*parser.RecordPattern]*ClassDef
+	aliases     map[parser.TypeNode]*aliasType
+	// constructors holds the schemes bound to constructor names, so a
+	// let that rebinds one is not mistaken for a constructor.
+	constructors map[*Scheme]bool
+	// importedFrom holds the modules each imported value was imported
+	// from, sorted. More than one means two imports bind the same name.
+	importedFrom map[*Scheme][]string
+	holes        []hole
+	// declaring holds the schemes that the declarations being checked are
+	// bound to, innermost last, so a hole does not suggest the binding it
+	// is part of.
+	declaring []*Scheme
+	// returns is the return type of the function being checked, or nil
+	// outside any function.
+	returns Type
+	pending []pendingConstraint
+	subst   Subst
 }
 
 func (checker *Checker) freshVar() *TypeVar {
@@ -16,6 +44,17 @@ func (checker *Checker) freshVar() *TypeVar {
 	return tv
 }
 
+// unify unifies a and b in the checker's substitution, which is threaded
+// through the whole module so solutions found in one expression are
+// visible to every later one.
+func (checker *Checker) unify(a, b Type) error {
+	return unify(a, b, checker.subst)
+}
+
+func (checker *Checker) apply(t Type) Type {
+	return apply(t, checker.subst)
+}
+
 func apply(t Type, s Subst) Type {
 	switch t := t.(type) {
 	case *TypeVar:
@@ -25,6 +64,12 @@ func apply(t Type, s Subst) Type {
 		return t
 	case *ListType:
 		return &ListType{Element: apply(t.Element, s)}
+	case *TupleType:
+		elems := make([]Type, len(t.Elements))
+		for i, e := range t.Elements {
+			elems[i] = apply(e, s)
+		}
+		return &TupleType{Elements: elems}
 	case *FunctionType:
 		params := make([]Type, len(t.Parameters))
 		for i, p := range t.Parameters {
@@ -34,6 +79,15 @@ func apply(t Type, s Subst) Type {
 			Parameters: params,
 			Return:     apply(t.Return, s),
 		}
+	case *NamedType:
+		args := make([]Type, len(t.Arguments))
+		for i, a := range t.Arguments {
+			args[i] = apply(a, s)
+		}
+		return &NamedType{
+			Name:      t.Name,
+			Arguments: args,
+		}
 	default:
 		return t
 	}
@@ -43,33 +97,42 @@ func unify(a, b Type, s Subst) error {
 	a = apply(a, s)
 	b = apply(b, s)
 	if av, ok := a.(*TypeVar); ok {
-		s[av.ID] = b
-		return nil
+		return bind(av, b, s)
 	}
 	if bv, ok := b.(*TypeVar); ok {
-		s[bv.ID] = a
-		return nil
+		return bind(bv, a, s)
 	}
 	switch a := a.(type) {
 	case *IntType, *FloatType, *BoolType,
 		*StringType, *CharType, *UnitType:
 		if a.String() != b.String() {
-			return fmt.Errorf("type mismatch: %s vs %s", a, b)
+			return failure("type mismatch: %s vs %s", a, b)
 		}
 		return nil
 	case *ListType:
 		bt, ok := b.(*ListType)
 		if !ok {
-			return fmt.Errorf("expected list, got %s", b)
+			return failure("expected list, got %s", b)
 		}
 		return unify(a.Element, bt.Element, s)
+	case *TupleType:
+		bt, ok := b.(*TupleType)
+		if !ok || len(a.Elements) != len(bt.Elements) {
+			return failure("type mismatch: %s vs %s", a, b)
+		}
+		for i := range a.Elements {
+			if err := unify(a.Elements[i], bt.Elements[i], s); err != nil {
+				return err
+			}
+		}
+		return nil
 	case *FunctionType:
 		bt, ok := b.(*FunctionType)
 		if !ok {
-			return fmt.Errorf("expected function, got %s", b)
+			return failure("expected function, got %s", b)
 		}
 		if len(a.Parameters) != len(bt.Parameters) {
-			return fmt.Errorf("arity mismatch")
+			return failure("type mismatch: %s vs %s", a, b)
 		}
 		for i := range a.Parameters {
 			if err := unify(a.Parameters[i], bt.Parameters[i], s); err != nil {
@@ -77,6 +140,37 @@ func unify(a, b Type, s Subst) error {
 			}
 		}
 		return unify(a.Return, bt.Return, s)
+	case *NamedType:
+		bt, ok := b.(*NamedType)
+		if !ok || a.Name != bt.Name || len(a.Arguments) != len(bt.Arguments) {
+			return failure("type mismatch: %s vs %s", a, b)
+		}
+		for i := range a.Arguments {
+			if err := unify(a.Arguments[i], bt.Arguments[i], s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return failure("type mismatch: %s vs %s", a, b)
+}
+
+func bind(v *TypeVar, t Type, s Subst) error {
+	if tv, ok := t.(*TypeVar); ok && tv.ID == v.ID {
+		return nil
 	}
-	return fmt.Errorf("cannot unify %T and %T", a, b)
+	if contains(freeTypeVars(t), v.ID) {
+		return &infiniteTypeError{v: v, t: t}
+	}
+	s[v.ID] = t
+	return nil
+}
+
+type infiniteTypeError struct {
+	v *TypeVar
+	t Type
+}
+
+func (e *infiniteTypeError) Error() string {
+	return fmt.Sprintf("infinite type: %s occurs in %s", e.v, e.t)
 }